@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: © 2022 Grégoire Duchêne <gduchene@awhk.org>
+// SPDX-License-Identifier: ISC
+
+// Package featurevar publishes features as expvars. It lives in its own
+// package because importing expvar registers a handler for /debug/vars
+// on http.DefaultServeMux, which users of core should opt into.
+package featurevar
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+
+	"go.awhk.org/core"
+)
+
+// ErrPublished is an error wrapped and returned by Publish if an expvar
+// with the same name already exists.
+var ErrPublished = errors.New("expvar already published")
+
+// Publish publishes the state of the feature passed as an expvar named
+// after the feature. The expvar reflects changes made at run time.
+func Publish(f *core.Feature) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if expvar.Get(f.Name) != nil {
+		return fmt.Errorf("%w: %s", ErrPublished, f.Name)
+	}
+	expvar.Publish(f.Name, expvar.Func(func() any { return f.Enabled() }))
+	return nil
+}
+
+var mu sync.Mutex
@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: © 2022 Grégoire Duchêne <gduchene@awhk.org>
+// SPDX-License-Identifier: ISC
+
+package featurevar_test
+
+import (
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"go.awhk.org/core"
+	"go.awhk.org/core/featurevar"
+)
+
+func TestPublish(s *testing.T) {
+	t := core.T{T: s}
+
+	// Expvars cannot be unpublished, so each run needs its own name.
+	f := core.Feature{Name: fmt.Sprintf("%s-%d", s.Name(), runs.Add(1))}
+	t.AssertErrorIs(nil, featurevar.Publish(&f))
+	t.AssertEqual("false", expvar.Get(f.Name).String())
+	f.Enable()
+	t.AssertEqual("true", expvar.Get(f.Name).String())
+
+	g := core.Feature{Name: f.Name}
+	t.AssertErrorIs(featurevar.ErrPublished, featurevar.Publish(&g))
+}
+
+var runs atomic.Int64
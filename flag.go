@@ -4,17 +4,32 @@
 package core
 
 import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// ErrInvalidBool is an error wrapped and returned by ParseBoolExtended
+// if the string passed is not a known boolean value.
+var ErrInvalidBool = errors.New("invalid boolean value")
+
+// ErrTooManyValues is an error wrapped and returned when setting a flag
+// created with the MaxValues option would exceed its limit.
+var ErrTooManyValues = errors.New("too many values")
+
 // ErrStringRegexpNoMatch is an error wrapped and returned by functions
 // created by ParseStringRegexp if the string passed did not match the
 // regular expression used.
@@ -35,6 +50,43 @@ func FlagVar[T any](fs *flag.FlagSet, p *T, name string, usage string, parse Par
 	fs.Var(&flagValue[T]{Parse: parse, Value: p}, name, usage)
 }
 
+// FlagCSVSlice works like FlagSlice, except each argument is parsed as
+// a single CSV record with a ‘,’ separator, so that values can contain
+// commas when quoted. Quoting follows RFC 4180, i.e. a value containing
+// commas or double quotes must be enclosed in double quotes, and double
+// quotes inside it must be doubled. For instance, ‘a,"b,c",d’ yields
+// ‘a,’ ‘b,c,’ and ‘d,’ while ‘"say ""hi"""’ yields ‘say "hi".’
+// Values are quoted the same way when the flag is printed.
+func FlagCSVSlice[T any](fs *flag.FlagSet, name string, values []T, usage string, parse ParseFunc[T], opts ...FlagSliceOption) *[]T {
+	p := make([]T, len(values))
+	copy(p, values)
+	FlagCSVSliceVar(fs, &p, name, usage, parse, opts...)
+	return &p
+}
+
+// FlagCSVSliceVar works like FlagCSVSlice, except it is up to the
+// caller to supply a valid *[]T.
+func FlagCSVSliceVar[T any](fs *flag.FlagSet, p *[]T, name string, usage string, parse ParseFunc[T], opts ...FlagSliceOption) {
+	f := newFlagValueSlice(name, p, parse, ",", nil, opts)
+	f.CSV = true
+	fs.Var(f, name, usage)
+}
+
+// FlagOptional works like Flag, except the value returned is None until
+// the flag is set, which tells apart flags that were not set from flags
+// set to their zero value.
+func FlagOptional[T any](fs *flag.FlagSet, name, usage string, parse ParseFunc[T]) *Optional[T] {
+	p := None[T]()
+	FlagOptionalVar(fs, &p, name, usage, parse)
+	return &p
+}
+
+// FlagOptionalVar works like FlagOptional, except it is up to the
+// caller to supply a valid *Optional[T].
+func FlagOptionalVar[T any](fs *flag.FlagSet, p *Optional[T], name, usage string, parse ParseFunc[T]) {
+	fs.Var(&flagOptional[T]{Parse: parse, Value: p}, name, usage)
+}
+
 // FlagSlice works like FlagT, except slices are created; flags created
 // that way can therefore be repeated. A valid *[]T is returned for use
 // by the caller.
@@ -47,69 +99,132 @@ func FlagVar[T any](fs *flag.FlagSet, p *T, name string, usage string, parse Par
 // - -flag=val -flag=val-2 -flag=val-3
 // - -flag=val,val-2 -flag=val-3
 // - -flag=val,val-2,val-3
-func FlagSlice[T any](fs *flag.FlagSet, name string, values []T, usage string, parse ParseFunc[T], sep string) *[]T {
+//
+// When set, the separator is also used to join values when the flag is
+// printed, e.g. in usage messages.
+//
+// Options can be passed to further control how values are set, see
+// FlagSliceOption.
+func FlagSlice[T any](fs *flag.FlagSet, name string, values []T, usage string, parse ParseFunc[T], sep string, opts ...FlagSliceOption) *[]T {
 	p := make([]T, len(values))
 	copy(p, values)
-	FlagSliceVar(fs, &p, name, usage, parse, sep)
+	FlagSliceVar(fs, &p, name, usage, parse, sep, opts...)
 	return &p
 }
 
 // FlagSliceVar works like FlagTSlice, except it is up to the caller to
 // supply a valid *[]T.
-func FlagSliceVar[T any](fs *flag.FlagSet, p *[]T, name string, usage string, parse ParseFunc[T], sep string) {
-	fs.Var(&flagValueSlice[T]{Parse: parse, Separator: sep, Values: p}, name, usage)
+func FlagSliceVar[T any](fs *flag.FlagSet, p *[]T, name string, usage string, parse ParseFunc[T], sep string, opts ...FlagSliceOption) {
+	fs.Var(newFlagValueSlice(name, p, parse, sep, nil, opts), name, usage)
+}
+
+// EnvName registers envVar as the environment variable InitFlagSet and
+// similar functions use for the flag named name in fs, instead of the
+// name they derive from the flag name, e.g. ‘SOME_FLAG’ for
+// ‘some-flag.’ Registrations are never forgotten, so EnvName should
+// only be used with long-lived flag sets.
+func EnvName(fs *flag.FlagSet, name, envVar string) {
+	envNamesMu.Lock()
+	defer envNamesMu.Unlock()
+
+	if envNames[fs] == nil {
+		envNames[fs] = map[string]string{}
+	}
+	envNames[fs][name] = envVar
+}
+
+// FlagSliceOption describes options that can be passed to FlagSlice and
+// similar functions.
+type FlagSliceOption func(*flagSliceOptions)
+
+// MaxValues is a FlagSliceOption that limits the number of values a
+// flag can hold to n. Setting more values than that returns an error
+// wrapping ErrTooManyValues. Values set by InitFlagSet from the
+// environment or a map are replaced rather than appended to by command
+// line arguments, so they do not count towards the limit then.
+func MaxValues(n int) FlagSliceOption {
+	return func(opts *flagSliceOptions) { opts.max = n }
+}
+
+// SkipEmpty is a FlagSliceOption that makes a flag ignore empty values,
+// e.g. those produced by ‘a,,b’ or ‘a,b,’ with a ‘,’ separator, instead
+// of passing them to the ParseFunc.
+func SkipEmpty() FlagSliceOption {
+	return func(opts *flagSliceOptions) { opts.skipEmpty = true }
+}
+
+// FlagUniqueSlice works like FlagSlice, except values that were already
+// set are skipped, so that the slice only contains the first occurrence
+// of each value. This also applies to values passed as a single
+// argument with a separator. Values are compared with ‘==’, so T must
+// be comparable.
+func FlagUniqueSlice[T comparable](fs *flag.FlagSet, name string, values []T, usage string, parse ParseFunc[T], sep string, opts ...FlagSliceOption) *[]T {
+	p := make([]T, len(values))
+	copy(p, values)
+	FlagUniqueSliceVar(fs, &p, name, usage, parse, sep, opts...)
+	return &p
+}
+
+// FlagUniqueSliceVar works like FlagUniqueSlice, except it is up to the
+// caller to supply a valid *[]T.
+func FlagUniqueSliceVar[T comparable](fs *flag.FlagSet, p *[]T, name string, usage string, parse ParseFunc[T], sep string, opts ...FlagSliceOption) {
+	equal := func(x, y T) bool { return x == y }
+	fs.Var(newFlagValueSlice(name, p, parse, sep, equal, opts), name, usage)
 }
 
 // InitFlagSet initializes a flag.FlagSet by setting flags in the
 // following order: environment variables, then an arbitrary map, then
 // command line arguments.
 //
+// Environment variables are passed as ‘NAME=value’ strings, or as bare
+// names to be looked up in the environment of the process. Boolean
+// flags are enabled by environment variables that are present but
+// empty, mirroring how ‘-flag’ works on the command line.
+//
+// Options can be passed to further control how values are set, see
+// InitFlagSetOption.
+//
 // Note that InitFlagSet does not require the use of the Flag functions
 // defined in this package. Standard flags will work just as well.
-func InitFlagSet(fs *flag.FlagSet, env []string, cfg map[string]string, args []string) (err error) {
-	var environ map[string]string
-	if env != nil {
-		environ = make(map[string]string, len(env))
-		for _, kv := range env {
-			if buf := strings.SplitN(kv, "=", 2); len(buf) == 2 {
-				environ[buf[0]] = buf[1]
-				continue
-			}
-			if val, ok := os.LookupEnv(kv); ok {
-				environ[kv] = val
-			}
-		}
-	}
-
-	fs.VisitAll(func(f *flag.Flag) {
-		if err != nil {
-			return
-		}
+func InitFlagSet(fs *flag.FlagSet, env []string, cfg map[string]string, args []string, opts ...InitFlagSetOption) error {
+	return initFlagSet(fs, env, cfg, args, newInitFlagSetOptions(opts))
+}
 
-		if f.DefValue != f.Value.String() {
-			if _, ok := f.Value.(interface{ MutableFlag() }); !ok {
-				return
-			}
-		}
+// InitFlagSetPrefix works like InitFlagSet, except prefix is prepended
+// to the environment variable name derived from each flag name, e.g.
+// ‘MYAPP_SOME_FLAG’ for ‘some-flag’ with a ‘MYAPP_’ prefix. Names
+// registered with EnvName are used as is.
+func InitFlagSetPrefix(fs *flag.FlagSet, prefix string, env []string, cfg map[string]string, args []string, opts ...InitFlagSetOption) error {
+	o := newInitFlagSetOptions(opts)
+	o.prefix = prefix
+	return initFlagSet(fs, env, cfg, args, o)
+}
 
-		var next string
-		if val, found := environ[strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))]; found {
-			next = val
-		}
-		if val, found := cfg[f.Name]; found {
-			next = val
-		}
-		if next != "" {
-			err = f.Value.Set(next)
-		}
-		if f, ok := f.Value.(interface{ resetShouldAppend() }); ok {
-			f.resetShouldAppend()
-		}
-	})
-	if err == nil && !fs.Parsed() {
-		return fs.Parse(args)
+// InitFlagSetTraced works like InitFlagSet, except it also returns a
+// map indicating where the value of each flag came from: "env", "cfg",
+// "args", "preset" if the flag had already been changed from its
+// default before InitFlagSetTraced was called and was not set again,
+// or "default" otherwise.
+func InitFlagSetTraced(fs *flag.FlagSet, env []string, cfg map[string]string, args []string, opts ...InitFlagSetOption) (map[string]string, error) {
+	sources := map[string]string{}
+	o := newInitFlagSetOptions(opts)
+	o.sources = sources
+	if err := initFlagSet(fs, env, cfg, args, o); err != nil {
+		return nil, err
 	}
-	return err
+	return sources, nil
+}
+
+// InitFlagSetOption describes options that can be passed to InitFlagSet
+// and similar functions.
+type InitFlagSetOption func(*initFlagSetOptions)
+
+// UnquoteValues is an InitFlagSetOption that strips values coming from
+// env and cfg of surrounding whitespace, then of one layer of matching
+// single or double quotes. This is useful when values come from files
+// where quoting is customary, e.g. ‘.env’ files.
+func UnquoteValues() InitFlagSetOption {
+	return func(opts *initFlagSetOptions) { opts.unquote = true }
 }
 
 // Feature represent a code feature that can be enabled and disabled.
@@ -120,6 +235,35 @@ type Feature struct {
 
 	_       NoCopy
 	enabled int32
+	mu      sync.Mutex
+	timer   *time.Timer
+}
+
+// AllFeatures returns a function that reports whether all the features
+// passed are enabled. Features are checked every time the function is
+// called, so it reflects changes made at run time.
+func AllFeatures(fs ...*Feature) func() bool {
+	return func() bool {
+		for _, f := range fs {
+			if !f.Enabled() {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyFeatures works like AllFeatures, except the returned function
+// reports whether at least one of the features passed is enabled.
+func AnyFeatures(fs ...*Feature) func() bool {
+	return func() bool {
+		for _, f := range fs {
+			if f.Enabled() {
+				return true
+			}
+		}
+		return false
+	}
 }
 
 // FlagFeature creates a feature that, i.e. a boolean flag that can
@@ -133,14 +277,74 @@ func FlagFeature(fs *flag.FlagSet, name string, enabled bool, usage string) *Fea
 	return f
 }
 
+// FlagFeatureEnv works like FlagFeature, except the feature is
+// initially enabled or disabled according to the envVar environment
+// variable, parsed with strconv.ParseBool. If envVar is not set or
+// cannot be parsed, fallback is used instead.
+func FlagFeatureEnv(fs *flag.FlagSet, name, envVar string, fallback bool, usage string) *Feature {
+	enabled := fallback
+	if val, found := os.LookupEnv(envVar); found {
+		if b, err := strconv.ParseBool(val); err == nil {
+			enabled = b
+		}
+	}
+	return FlagFeature(fs, name, enabled, usage)
+}
+
 func FlagFeatureVar(fs *flag.FlagSet, f *Feature, name, usage string) {
 	fs.Var(flagFeature{f}, name, usage)
 }
 
-func (f *Feature) Disable()      { atomic.StoreInt32(&f.enabled, 0) }
-func (f *Feature) Enable()       { atomic.StoreInt32(&f.enabled, 1) }
+// Disable disables the feature, cancelling any pending EnableFor.
+func (f *Feature) Disable() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopTimerLocked()
+	atomic.StoreInt32(&f.enabled, 0)
+}
+
+// Enable enables the feature, cancelling any pending EnableFor.
+func (f *Feature) Enable() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopTimerLocked()
+	atomic.StoreInt32(&f.enabled, 1)
+}
+
+// EnableFor enables the feature, then disables it after d. Calling
+// Disable, Enable, or EnableFor again before then cancels the pending
+// disabling.
+func (f *Feature) EnableFor(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopTimerLocked()
+	atomic.StoreInt32(&f.enabled, 1)
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		// The timer may have fired right before being stopped, in
+		// which case it must not disable the feature.
+		if f.timer == timer {
+			f.timer = nil
+			atomic.StoreInt32(&f.enabled, 0)
+		}
+	})
+	f.timer = timer
+}
+
 func (f *Feature) Enabled() bool { return atomic.LoadInt32(&f.enabled) == 1 }
 
+// MarshalText returns ‘true’ or ‘false’ depending on whether the
+// feature is enabled, unlike String which is meant for humans.
+func (f *Feature) MarshalText() ([]byte, error) {
+	return strconv.AppendBool(nil, f.Enabled()), nil
+}
+
 func (f *Feature) String() string {
 	return fmt.Sprintf("%s (enabled: %t)", f.Name, f.Enabled())
 }
@@ -149,6 +353,71 @@ func (f *Feature) String() string {
 // value or an error.
 type ParseFunc[T any] func(string) (T, error)
 
+// ParseBase64Bytes decodes s with the standard base64 encoding, padding
+// included. Errors returned wrap a base64.CorruptInputError.
+func ParseBase64Bytes(s string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 value %q: %w", s, err)
+	}
+	return b, nil
+}
+
+// ParseBoolExtended works like strconv.ParseBool, except it also
+// accepts ‘yes,’ ‘no,’ ‘on,’ and ‘off.’ Comparison is case-insensitive.
+func ParseBoolExtended(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "1", "t", "true", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w %q", ErrInvalidBool, s)
+}
+
+// ParseFloat returns a ParseFunc that parses floating-point numbers
+// with strconv.ParseFloat and the bitSize passed. Errors returned are
+// *strconv.NumError values, which carry the string that was passed.
+func ParseFloat(bitSize int) ParseFunc[float64] {
+	return func(s string) (float64, error) {
+		val, err := strconv.ParseFloat(s, bitSize)
+		if err != nil {
+			return 0, err
+		}
+		return val, nil
+	}
+}
+
+// ParseHexBytes decodes s as a hexadecimal string, in either case.
+// Errors returned wrap hex.ErrLength or a hex.InvalidByteError.
+func ParseHexBytes(s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex value %q: %w", s, err)
+	}
+	return b, nil
+}
+
+// ParseInt returns a ParseFunc that parses integers with
+// strconv.ParseInt and the base and bitSize passed. A base of 0 means
+// the base is deduced from the string prefix, e.g. ‘0x’ for
+// hexadecimal. Values that fit in bitSize bits but not in T are
+// rejected like values that do not fit in bitSize bits are. Errors
+// returned are *strconv.NumError values, which carry the string that
+// was passed.
+func ParseInt[T Signed](base, bitSize int) ParseFunc[T] {
+	return func(s string) (T, error) {
+		val, err := strconv.ParseInt(s, base, bitSize)
+		if err != nil {
+			return 0, err
+		}
+		if int64(T(val)) != val {
+			return 0, &strconv.NumError{Func: "ParseInt", Num: s, Err: strconv.ErrRange}
+		}
+		return T(val), nil
+	}
+}
+
 // ParseProtobufEnum returns a ParseFunc that will return the
 // appropriate enum value or a UnknownEnumValueError if the string
 // passed did not match any of the values supplied.
@@ -159,16 +428,38 @@ type ParseFunc[T any] func(string) (T, error)
 // Callers should pass the protoc-generated *_value directly. See
 // https://developers.google.com/protocol-buffers/docs/reference/go-generated#enum
 // for more details.
+//
+// The expected values carried by UnknownEnumValueError are sorted, so
+// that error messages are stable.
 func ParseProtobufEnum[T ~int32](values map[string]int32) ParseFunc[T] {
+	expected := MapKeys(values)
+	sort.Strings(expected)
 	return func(s string) (T, error) {
 		val, found := values[strings.ToUpper(s)]
 		if !found {
-			return 0, UnknownEnumValueError[string]{s, MapKeys(values)}
+			return 0, UnknownEnumValueError[string]{s, expected}
 		}
 		return T(val), nil
 	}
 }
 
+// ParseSlogLevel parses ‘debug,’ ‘info,’ ‘warn,’ or ‘error’ into the
+// matching slog.Level. Comparison is case-insensitive. Any other value
+// results in an UnknownEnumValueError.
+func ParseSlogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, UnknownEnumValueError[string]{s, []string{"debug", "info", "warn", "error"}}
+}
+
 // ParseString is a trivial function that is designed to be used with
 // FlagSlice and FlagSliceVar.
 func ParseString(s string) (string, error) { return s, nil }
@@ -220,9 +511,18 @@ func ParseTime(s string) (time.Time, error) {
 	return time.Parse(time.RFC3339, s)
 }
 
-// UnknownEnumValueError is returned by the functions produced by
-// ParseProtobufEnum and ParseStringEnum when an unknown value is
-// encountered.
+// ParseTimeLayout returns a ParseFunc that parses a string according to
+// the layout passed. Errors returned are *time.ParseError values, which
+// carry both the string passed and the layout.
+func ParseTimeLayout(layout string) ParseFunc[time.Time] {
+	return func(s string) (time.Time, error) {
+		return time.Parse(layout, s)
+	}
+}
+
+// UnknownEnumValueError is returned by ParseSlogLevel and by the
+// functions produced by ParseProtobufEnum and ParseStringEnum when an
+// unknown value is encountered.
 type UnknownEnumValueError[T any] struct {
 	Actual   string
 	Expected []T
@@ -232,6 +532,13 @@ func (err UnknownEnumValueError[T]) Error() string {
 	return fmt.Sprintf("unknown value %s, expected one of %v", err.Actual, err.Expected)
 }
 
+func (f *Feature) stopTimerLocked() {
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+}
+
 type flagFeature struct{ *Feature }
 
 func (flagFeature) IsBoolFlag() bool { return true }
@@ -257,6 +564,30 @@ func (f flagFeature) String() string {
 	return "false"
 }
 
+type flagOptional[T any] struct {
+	Parse ParseFunc[T]
+	Value *Optional[T]
+}
+
+func (f *flagOptional[T]) Set(s string) error {
+	val, err := f.Parse(s)
+	if err != nil {
+		return err
+	}
+	*f.Value = Some(val)
+	return nil
+}
+
+func (f *flagOptional[T]) String() string {
+	if f.Value == nil {
+		return ""
+	}
+	if val, ok := f.Value.Get(); ok {
+		return fmt.Sprintf("%v", val)
+	}
+	return ""
+}
+
 type flagValue[T any] struct {
 	Parse ParseFunc[T]
 	Value *T
@@ -279,40 +610,220 @@ func (f *flagValue[T]) String() string {
 	return fmt.Sprintf("%v", *f.Value)
 }
 
+type flagSliceOptions struct {
+	max       int
+	skipEmpty bool
+}
+
 type flagValueSlice[T any] struct {
+	CSV       bool
+	Equal     func(T, T) bool
+	Name      string
 	Parse     ParseFunc[T]
 	Separator string
 	Values    *[]T
 
+	flagSliceOptions
 	shouldAppend bool
 }
 
+func newFlagValueSlice[T any](name string, p *[]T, parse ParseFunc[T], sep string, equal func(T, T) bool, opts []FlagSliceOption) *flagValueSlice[T] {
+	f := &flagValueSlice[T]{Equal: equal, Name: name, Parse: parse, Separator: sep, Values: p}
+	for _, opt := range opts {
+		opt(&f.flagSliceOptions)
+	}
+	return f
+}
+
 func (f *flagValueSlice[T]) Set(s string) error {
 	vals := []string{s}
-	if f.Separator != "" {
+	if f.CSV {
+		r := csv.NewReader(strings.NewReader(s))
+		r.FieldsPerRecord = -1
+		record, err := r.Read()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("invalid CSV record %q for flag -%s: %w", s, f.Name, err)
+		}
+		if _, err := r.Read(); err != io.EOF {
+			return fmt.Errorf("invalid CSV record %q for flag -%s: more than one record", s, f.Name)
+		}
+		vals = record
+		if vals == nil {
+			vals = []string{""}
+		}
+	} else if f.Separator != "" {
 		vals = strings.Split(s, f.Separator)
 	}
 	for _, val := range vals {
+		if f.skipEmpty && val == "" {
+			continue
+		}
 		parsed, err := f.Parse(val)
 		if err != nil {
 			return err
 		}
-		if f.shouldAppend {
-			*f.Values = append(*f.Values, parsed)
-		} else {
-			*f.Values = []T{parsed}
+		if !f.shouldAppend {
+			*f.Values = nil
 			f.shouldAppend = true
+		} else if f.contains(parsed) {
+			continue
+		}
+		if f.max > 0 && len(*f.Values) >= f.max {
+			return fmt.Errorf("%w for flag -%s, expected at most %d", ErrTooManyValues, f.Name, f.max)
 		}
+		*f.Values = append(*f.Values, parsed)
 	}
 	return nil
 }
 
 func (f *flagValueSlice[T]) String() string {
-	if f.Values == nil {
-		var zero []T
-		return fmt.Sprintf("%v", zero)
+	var vals []T
+	if f.Values != nil {
+		vals = *f.Values
 	}
-	return fmt.Sprintf("%v", *f.Values)
+	// Empty slices must print the same regardless of the separator, as
+	// flag.PrintDefaults compares DefValue to the String of a zero
+	// value to tell whether to print a default.
+	if len(vals) == 0 {
+		return ""
+	}
+	if f.Separator == "" {
+		return fmt.Sprintf("%v", vals)
+	}
+	if f.CSV {
+		var b strings.Builder
+		w := csv.NewWriter(&b)
+		w.Write(SliceMap(func(val T) string { return fmt.Sprint(val) }, vals))
+		w.Flush()
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+	return strings.Join(SliceMap(func(val T) string { return fmt.Sprint(val) }, vals), f.Separator)
+}
+
+func (f *flagValueSlice[T]) contains(val T) bool {
+	if f.Equal == nil {
+		return false
+	}
+	for _, v := range *f.Values {
+		if f.Equal(v, val) {
+			return true
+		}
+	}
+	return false
 }
 
 func (f *flagValueSlice[T]) resetShouldAppend() { f.shouldAppend = false }
+
+var (
+	envNames   = map[*flag.FlagSet]map[string]string{}
+	envNamesMu sync.Mutex
+)
+
+func envName(fs *flag.FlagSet, name, prefix string) string {
+	envNamesMu.Lock()
+	defer envNamesMu.Unlock()
+
+	if envVar, found := envNames[fs][name]; found {
+		return envVar
+	}
+	return prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+type initFlagSetOptions struct {
+	prefix  string
+	sources map[string]string
+	unquote bool
+}
+
+func newInitFlagSetOptions(opts []InitFlagSetOption) initFlagSetOptions {
+	var o initFlagSetOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func initFlagSet(fs *flag.FlagSet, env []string, cfg map[string]string, args []string, opts initFlagSetOptions) (err error) {
+	sources := opts.sources
+	var environ map[string]string
+	if env != nil {
+		environ = make(map[string]string, len(env))
+		for _, kv := range env {
+			if buf := strings.SplitN(kv, "=", 2); len(buf) == 2 {
+				environ[buf[0]] = buf[1]
+				continue
+			}
+			if val, found := os.LookupEnv(kv); found {
+				environ[kv] = val
+			}
+		}
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		if sources != nil {
+			sources[f.Name] = "default"
+			if f.DefValue != f.Value.String() {
+				sources[f.Name] = "preset"
+			}
+		}
+
+		if f.DefValue != f.Value.String() {
+			if _, ok := f.Value.(interface{ MutableFlag() }); !ok {
+				return
+			}
+		}
+
+		var next, source string
+		if val, found := environ[envName(fs, f.Name, opts.prefix)]; found {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() && val == "" {
+				val = "true"
+			}
+			next, source = val, "env"
+		}
+		if val, found := cfg[f.Name]; found {
+			next, source = val, "cfg"
+		}
+		if opts.unquote {
+			next = unquote(next)
+		}
+		if next != "" {
+			if err = f.Value.Set(next); err == nil && sources != nil {
+				sources[f.Name] = source
+			}
+		}
+		if f, ok := f.Value.(interface{ resetShouldAppend() }); ok {
+			f.resetShouldAppend()
+		}
+	})
+	if err != nil || fs.Parsed() {
+		return err
+	}
+	if sources == nil {
+		return fs.Parse(args)
+	}
+
+	// fs.Visit reports the flags set by args, but also those set with
+	// fs.Set before InitFlagSetTraced was called. The latter can only be
+	// attributed to args if parsing them changed their value.
+	preset := map[string]string{}
+	fs.Visit(func(f *flag.Flag) { preset[f.Name] = f.Value.String() })
+	if err = fs.Parse(args); err == nil {
+		fs.Visit(func(f *flag.Flag) {
+			if val, found := preset[f.Name]; !found || val != f.Value.String() {
+				sources[f.Name] = "args"
+			}
+		})
+	}
+	return err
+}
+
+func unquote(s string) string {
+	s = strings.Trim(s, " \t\n\v\f\r")
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -4,16 +4,67 @@
 package core_test
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
+	"io"
+	"log/slog"
 	"regexp"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
 	"go.awhk.org/core"
 )
 
+func TestAllFeatures(s *testing.T) {
+	t := core.T{T: s}
+
+	var f1, f2 core.Feature
+	enabled := core.AllFeatures(&f1, &f2)
+	t.AssertEqual(false, enabled())
+	f1.Enable()
+	t.AssertEqual(false, enabled())
+	f2.Enable()
+	t.AssertEqual(true, enabled())
+	f1.Disable()
+	t.AssertEqual(false, enabled())
+	t.AssertEqual(true, core.AllFeatures()())
+}
+
+func TestAnyFeatures(s *testing.T) {
+	t := core.T{T: s}
+
+	var f1, f2 core.Feature
+	enabled := core.AnyFeatures(&f1, &f2)
+	t.AssertEqual(false, enabled())
+	f1.Enable()
+	t.AssertEqual(true, enabled())
+	f2.Enable()
+	t.AssertEqual(true, enabled())
+	f1.Disable()
+	f2.Disable()
+	t.AssertEqual(false, enabled())
+	t.AssertEqual(false, core.AnyFeatures()())
+}
+
+func TestEnvName(s *testing.T) {
+	t := core.T{T: s}
+
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	fi := fs.Int("timeout", 0, "")
+	fm := fs.String("string", "", "")
+	core.EnvName(fs, "timeout", "MYAPP_TIMEOUT")
+	t.AssertErrorIs(nil, core.InitFlagSet(fs, []string{"TIMEOUT=21", "MYAPP_TIMEOUT=42", "STRING=Hello World!"}, nil, nil))
+	t.AssertEqual(42, *fi)
+	t.AssertEqual("Hello World!", *fm)
+}
+
 func TestFeature_Disable(t *testing.T) {
 	f := core.Feature{}
 	f.Disable()
@@ -26,6 +77,53 @@ func TestFeature_Enable(t *testing.T) {
 	(&core.T{T: t}).AssertEqual(true, f.Enabled())
 }
 
+func TestFeature_EnableFor(s *testing.T) {
+	t := core.T{T: s}
+
+	var f core.Feature
+	f.EnableFor(10 * time.Millisecond)
+	t.AssertEqual(true, f.Enabled())
+	deadline := time.Now().Add(time.Second)
+	for f.Enabled() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	t.AssertEqual(false, f.Enabled())
+
+	t.Run("WhenCancelled", func(t *core.T) {
+		var f1, f2, f3 core.Feature
+		f1.EnableFor(10 * time.Millisecond)
+		f1.Enable()
+		f2.EnableFor(10 * time.Millisecond)
+		f2.EnableFor(time.Hour)
+		f3.EnableFor(10 * time.Millisecond)
+		f3.Disable()
+		time.Sleep(50 * time.Millisecond)
+		t.AssertEqual(true, f1.Enabled())
+		t.AssertEqual(true, f2.Enabled())
+		t.AssertEqual(false, f3.Enabled())
+		f2.Disable()
+	})
+}
+
+func TestFeature_MarshalText(s *testing.T) {
+	t := core.T{T: s}
+
+	f := core.Feature{Name: "some-feature"}
+	text, err := f.MarshalText()
+	t.AssertErrorIs(nil, err)
+	t.AssertEqual("false", string(text))
+
+	f.Enable()
+	text, err = f.MarshalText()
+	t.AssertErrorIs(nil, err)
+	t.AssertEqual("true", string(text))
+
+	var g core.Feature
+	buf, err := json.Marshal(map[string]*core.Feature{"enabled": &f, "disabled": &g})
+	t.AssertErrorIs(nil, err)
+	t.AssertEqual(`{"disabled":"false","enabled":"true"}`, string(buf))
+}
+
 func TestFlag(s *testing.T) {
 	t := core.T{T: s}
 
@@ -36,6 +134,45 @@ func TestFlag(s *testing.T) {
 	t.AssertEqual(84, *fl)
 }
 
+func TestFlagCSVSlice(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		name string
+		args []string
+
+		exp    []string
+		expErr bool
+	}{
+		{name: "Simple", args: []string{"-tags=a,b"}, exp: []string{"a", "b"}},
+		{name: "EmbeddedSeparator", args: []string{`-tags=a,"b,c",d`}, exp: []string{"a", "b,c", "d"}},
+		{name: "EmbeddedQuotes", args: []string{`-tags="say ""hi""",x`}, exp: []string{`say "hi"`, "x"}},
+		{name: "Repeated", args: []string{"-tags=a", `-tags="b,c"`}, exp: []string{"a", "b,c"}},
+		{name: "WhenQuoteUnterminated", args: []string{`-tags="a,b`}, expErr: true},
+		{name: "WhenBareQuote", args: []string{`-tags=a"b`}, expErr: true},
+		{name: "WhenMultipleRecords", args: []string{"-tags=a\nb"}, expErr: true},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			fs := flag.NewFlagSet("", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			fl := core.FlagCSVSlice(fs, "tags", []string{"default"}, "", core.ParseString)
+			err := fs.Parse(tc.args)
+			if tc.expErr {
+				t.AssertNotNil(err)
+				return
+			}
+			t.AssertErrorIs(nil, err)
+			t.AssertEqual(tc.exp, *fl)
+		})
+	}
+
+	t.Run("String", func(t *core.T) {
+		fs := flag.NewFlagSet("", flag.PanicOnError)
+		core.FlagCSVSlice(fs, "tags", []string{"a", "b,c", `"d"`}, "", core.ParseString)
+		t.AssertEqual(`a,"b,c","""d"""`, fs.Lookup("tags").DefValue)
+	})
+}
+
 func TestFlagFeature(s *testing.T) {
 	t := core.T{T: s}
 
@@ -45,6 +182,74 @@ func TestFlagFeature(s *testing.T) {
 	t.AssertEqual(true, ff.Enabled())
 }
 
+func TestFlagFeatureEnv(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		name     string
+		env      string
+		fallback bool
+
+		exp bool
+	}{
+		{
+			name:     "Set",
+			env:      "true",
+			fallback: false,
+
+			exp: true,
+		},
+		{
+			name:     "SetFalse",
+			env:      "false",
+			fallback: true,
+
+			exp: false,
+		},
+		{
+			name:     "Unset",
+			fallback: true,
+
+			exp: true,
+		},
+		{
+			name:     "Unparseable",
+			env:      "maybe",
+			fallback: true,
+
+			exp: true,
+		},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			if tc.env != "" {
+				t.Setenv("SOME_FEATURE", tc.env)
+			}
+			fs := flag.NewFlagSet("", flag.PanicOnError)
+			ff := core.FlagFeatureEnv(fs, "some-feature", "SOME_FEATURE", tc.fallback, "")
+			t.AssertEqual(tc.exp, ff.Enabled())
+		})
+	}
+}
+
+func TestFlagOptional(s *testing.T) {
+	t := core.T{T: s}
+
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	fo := core.FlagOptional(fs, "test", "", strconv.Atoi)
+	fn := core.FlagOptional(fs, "unset", "", strconv.Atoi)
+	_, ok := fo.Get()
+	t.AssertEqual(false, ok)
+
+	t.AssertErrorIs(nil, fs.Parse([]string{"-test=0"}))
+	val, ok := fo.Get()
+	t.AssertEqual(0, val)
+	t.AssertEqual(true, ok)
+	_, ok = fn.Get()
+	t.AssertEqual(false, ok)
+	t.AssertEqual("0", fs.Lookup("test").Value.String())
+	t.AssertEqual("", fs.Lookup("unset").Value.String())
+}
+
 func TestFlagVar(s *testing.T) {
 	t := core.T{T: s}
 
@@ -66,6 +271,124 @@ func TestFlagSlice(s *testing.T) {
 	t.AssertEqual([]int{1, 2, 42, 84}, *fl)
 }
 
+func TestFlagSlice_MaxValues(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		name string
+		env  []string
+		args []string
+
+		exp    []int
+		expErr bool
+	}{
+		{
+			name: "Success",
+			args: []string{"-test=1", "-test=2,3"},
+
+			exp: []int{1, 2, 3},
+		},
+		{
+			name: "WhenRepeatedTooMuch",
+			args: []string{"-test=1", "-test=2", "-test=3", "-test=4"},
+
+			exp:    []int{1, 2, 3},
+			expErr: true,
+		},
+		{
+			name: "WhenSeparatedTooMuch",
+			args: []string{"-test=1,2,3,4"},
+
+			exp:    []int{1, 2, 3},
+			expErr: true,
+		},
+		{
+			name: "WhenEnvTooMuch",
+			env:  []string{"TEST=1,2,3,4"},
+
+			exp:    []int{1, 2, 3},
+			expErr: true,
+		},
+		{
+			name: "WhenReset",
+			env:  []string{"TEST=1,2,3"},
+			args: []string{"-test=4", "-test=5,6"},
+
+			exp: []int{4, 5, 6},
+		},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			fs := flag.NewFlagSet("", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			fl := core.FlagSlice(fs, "test", nil, "", strconv.Atoi, ",", core.MaxValues(3))
+			// The flag package does not wrap errors returned by Set, so
+			// the message has to be checked instead.
+			err := core.InitFlagSet(fs, tc.env, nil, tc.args)
+			if tc.expErr {
+				t.Assert(err != nil && strings.Contains(err.Error(), "too many values for flag -test, expected at most 3"))
+			} else {
+				t.AssertErrorIs(nil, err)
+			}
+			t.AssertEqual(tc.exp, *fl)
+		})
+	}
+}
+
+func TestFlagSlice_SkipEmpty(s *testing.T) {
+	t := core.T{T: s}
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fl := core.FlagSlice(fs, "test", nil, "", strconv.Atoi, ",", core.SkipEmpty())
+	t.AssertErrorIs(nil, fs.Parse([]string{"-test=,1,,2,", "-test=", "-test=,,3"}))
+	t.AssertEqual([]int{1, 2, 3}, *fl)
+
+	t.Run("NotByDefault", func(t *core.T) {
+		fs := flag.NewFlagSet("", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fl := core.FlagSlice(fs, "test", nil, "", core.ParseString, ",")
+		t.AssertErrorIs(nil, fs.Parse([]string{"-test=,a,,b,"}))
+		t.AssertEqual([]string{"", "a", "", "b", ""}, *fl)
+	})
+}
+
+func TestFlagSlice_String(s *testing.T) {
+	t := core.T{T: s}
+
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	core.FlagSlice(fs, "test", []int{1, 2}, "", strconv.Atoi, ",")
+	core.FlagSlice(fs, "empty", nil, "", strconv.Atoi, ",")
+	core.FlagSlice(fs, "no-sep", []int{1, 2}, "", strconv.Atoi, "")
+	core.FlagSlice(fs, "empty-no-sep", nil, "", strconv.Atoi, "")
+	t.AssertEqual("1,2", fs.Lookup("test").DefValue)
+	t.AssertEqual("", fs.Lookup("empty").DefValue)
+	t.AssertEqual("[1 2]", fs.Lookup("no-sep").DefValue)
+	t.AssertEqual("", fs.Lookup("empty-no-sep").DefValue)
+
+	t.AssertErrorIs(nil, fs.Parse([]string{"-test=42,84"}))
+	val := fs.Lookup("test").Value.String()
+	t.AssertEqual("42,84", val)
+
+	fl := core.FlagSlice(fs, "round-trip", nil, "", strconv.Atoi, ",")
+	t.AssertErrorIs(nil, fs.Set("round-trip", val))
+	t.AssertEqual([]int{42, 84}, *fl)
+}
+
+func TestFlagSlice_Usage(s *testing.T) {
+	t := core.T{T: s}
+
+	var buf strings.Builder
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.SetOutput(&buf)
+	core.FlagSlice(fs, "hosts", nil, "hosts to use", core.ParseString, ",")
+	core.FlagSlice(fs, "ports", []int{80, 443}, "ports to use", strconv.Atoi, ",")
+	core.FlagSlice(fs, "tags", nil, "tags to use", core.ParseString, "")
+	fs.PrintDefaults()
+	t.AssertEqual("  -hosts value\n    \thosts to use\n"+
+		"  -ports value\n    \tports to use (default 80,443)\n"+
+		"  -tags value\n    \ttags to use\n", buf.String())
+}
+
 func TestFlagSliceVar(s *testing.T) {
 	t := core.T{T: s}
 
@@ -77,6 +400,27 @@ func TestFlagSliceVar(s *testing.T) {
 	t.AssertEqual([]int{1, 2, 42, 84}, fl)
 }
 
+func TestFlagUniqueSlice(s *testing.T) {
+	t := core.T{T: s}
+
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	fl := core.FlagUniqueSlice(fs, "test", []int{42}, "", strconv.Atoi, ",")
+	t.AssertEqual([]int{42}, *fl)
+	t.AssertErrorIs(nil, fs.Parse([]string{"-test=2", "-test=1", "-test=2", "-test=42,1,84,42"}))
+	t.AssertEqual([]int{2, 1, 42, 84}, *fl)
+}
+
+func TestFlagUniqueSliceVar(s *testing.T) {
+	t := core.T{T: s}
+
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	fl := []string{"foo"}
+	core.FlagUniqueSliceVar(fs, &fl, "test", "", core.ParseString, ",")
+	t.AssertEqual([]string{"foo"}, fl)
+	t.AssertErrorIs(nil, fs.Parse([]string{"-test=bar,bar,baz", "-test=baz"}))
+	t.AssertEqual([]string{"bar", "baz"}, fl)
+}
+
 func TestInitFlagSet(s *testing.T) {
 	t := core.T{T: s}
 
@@ -145,8 +489,281 @@ func TestInitFlagSet(s *testing.T) {
 	})
 }
 
+func TestInitFlagSet_BoolFromEnv(s *testing.T) {
+	t := core.T{T: s}
+
+	t.Setenv("SOME_FEATURE", "")
+	for _, env := range []string{"SOME_FEATURE", "SOME_FEATURE="} {
+		t.Run(env, func(t *core.T) {
+			fs := flag.NewFlagSet("", flag.PanicOnError)
+			ff := core.FlagFeature(fs, "some-feature", false, "")
+			fb := fs.Bool("bool", false, "")
+			fi := fs.Int("int", 42, "")
+			t.AssertErrorIs(nil, core.InitFlagSet(fs, []string{env, "BOOL=", "INT="}, nil, nil))
+			t.AssertEqual(true, ff.Enabled())
+			t.AssertEqual(true, *fb)
+			t.AssertEqual(42, *fi)
+		})
+	}
+
+	t.Run("WhenAbsent", func(t *core.T) {
+		fs := flag.NewFlagSet("", flag.PanicOnError)
+		fb := fs.Bool("core-test-debug", false, "")
+		t.AssertErrorIs(nil, core.InitFlagSet(fs, []string{"CORE_TEST_DEBUG"}, nil, nil))
+		t.AssertEqual(false, *fb)
+	})
+}
+
+func TestInitFlagSet_UnquoteValues(s *testing.T) {
+	t := core.T{T: s}
+
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	fi := fs.Int("int", 0, "")
+	fl := core.FlagSlice(fs, "int-slice", nil, "", strconv.Atoi, ",")
+	fm := fs.String("string", "", "")
+	fq := fs.String("quote", "", "")
+	t.AssertErrorIs(nil, core.InitFlagSet(
+		fs,
+		[]string{`INT= "42" `, "QUOTE='\"'"},
+		map[string]string{"int-slice": "'42,84'\n", "string": ` "Hello World!'`},
+		nil,
+		core.UnquoteValues(),
+	))
+	t.AssertEqual(42, *fi)
+	t.AssertEqual([]int{42, 84}, *fl)
+	t.AssertEqual(`"Hello World!'`, *fm)
+	t.AssertEqual(`"`, *fq)
+
+	t.Run("WithPrefixAndTrace", func(t *core.T) {
+		fs := flag.NewFlagSet("", flag.PanicOnError)
+		fi := fs.Int("int", 0, "")
+		t.AssertErrorIs(nil, core.InitFlagSetPrefix(fs, "MYAPP_", []string{`MYAPP_INT="42"`}, nil, nil, core.UnquoteValues()))
+		t.AssertEqual(42, *fi)
+
+		fs = flag.NewFlagSet("", flag.PanicOnError)
+		fi = fs.Int("int", 0, "")
+		sources, err := core.InitFlagSetTraced(fs, []string{`INT="42"`}, nil, nil, core.UnquoteValues())
+		t.AssertErrorIs(nil, err)
+		t.AssertEqual(42, *fi)
+		t.AssertEqual(map[string]string{"int": "env"}, sources)
+	})
+
+	t.Run("NotByDefault", func(t *core.T) {
+		fs := flag.NewFlagSet("", flag.ContinueOnError)
+		fs.Int("int", 0, "")
+		t.AssertNotEqual(nil, core.InitFlagSet(fs, []string{`INT="42"`}, nil, nil))
+	})
+}
+
+func TestInitFlagSetPrefix(s *testing.T) {
+	t := core.T{T: s}
+
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	fi := fs.Int("timeout", 0, "")
+	fm := fs.String("some-string", "", "")
+	fb := fs.Bool("bool", false, "")
+	core.EnvName(fs, "bool", "ENABLE_BOOL")
+	t.AssertErrorIs(nil, core.InitFlagSetPrefix(
+		fs,
+		"MYAPP_",
+		[]string{"TIMEOUT=21", "MYAPP_TIMEOUT=42", "SOME_STRING=Hello World!", "ENABLE_BOOL=true"},
+		nil,
+		nil,
+	))
+	t.AssertEqual(42, *fi)
+	t.AssertEqual("", *fm)
+	t.AssertEqual(true, *fb)
+}
+
+func TestInitFlagSetTraced(s *testing.T) {
+	t := core.T{T: s}
+
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	fs.Int("int", 0, "")
+	core.FlagSlice(fs, "int-slice", nil, "", strconv.Atoi, ",")
+	fs.String("string", "", "")
+	fs.Bool("bool", false, "")
+	sources, err := core.InitFlagSetTraced(
+		fs,
+		[]string{"STRING=Hello World!"},
+		map[string]string{"string": "Hello Universe!", "int-slice": "42,84"},
+		[]string{"-int=42", "-int-slice=21,42"},
+	)
+	t.AssertErrorIs(nil, err)
+	t.AssertEqual(map[string]string{
+		"bool":      "default",
+		"int":       "args",
+		"int-slice": "args",
+		"string":    "cfg",
+	}, sources)
+
+	t.Run("WhenSetBefore", func(t *core.T) {
+		fs := flag.NewFlagSet("", flag.PanicOnError)
+		fs.Int("int", 0, "")
+		fs.String("string", "", "")
+		fs.Bool("bool", false, "")
+		t.AssertErrorIs(nil, fs.Set("int", "42"))
+		t.AssertErrorIs(nil, fs.Set("string", "Hello World!"))
+		sources, err := core.InitFlagSetTraced(fs, nil, nil, []string{"-string=Hello Universe!"})
+		t.AssertErrorIs(nil, err)
+		t.AssertEqual(map[string]string{
+			"bool":   "default",
+			"int":    "preset",
+			"string": "args",
+		}, sources)
+	})
+
+	t.Run("WhenChangedBefore", func(t *core.T) {
+		fs := flag.NewFlagSet("", flag.PanicOnError)
+		fi := fs.Int("int", 0, "")
+		*fi = 42
+		sources, err := core.InitFlagSetTraced(fs, []string{"INT=84"}, nil, nil)
+		t.AssertErrorIs(nil, err)
+		t.AssertEqual(42, *fi)
+		t.AssertEqual(map[string]string{"int": "preset"}, sources)
+	})
+
+	t.Run("WhenArgsRepeatValue", func(t *core.T) {
+		fs := flag.NewFlagSet("", flag.PanicOnError)
+		fs.Int("int", 0, "")
+		fs.String("string", "", "")
+		sources, err := core.InitFlagSetTraced(
+			fs,
+			[]string{"INT=42"},
+			map[string]string{"string": "Hello World!"},
+			[]string{"-int=42", "-string=Hello World!"},
+		)
+		t.AssertErrorIs(nil, err)
+		t.AssertEqual(map[string]string{"int": "args", "string": "args"}, sources)
+	})
+}
+
+func TestParseBase64Bytes(s *testing.T) {
+	t := core.T{T: s}
+
+	val, err := core.ParseBase64Bytes("SGVsbG8gV29ybGQh")
+	t.AssertErrorIs(nil, err)
+	t.AssertEqual([]byte("Hello World!"), val)
+
+	val, err = core.ParseBase64Bytes("SGVsbG8*")
+	var corruptErr base64.CorruptInputError
+	t.AssertErrorAs(&corruptErr, err)
+	t.Assert(strings.Contains(err.Error(), `"SGVsbG8*"`))
+	t.AssertEqual(([]byte)(nil), val)
+}
+
+func TestParseBoolExtended(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		input string
+
+		exp    bool
+		expErr error
+	}{
+		{input: "1", exp: true},
+		{input: "t", exp: true},
+		{input: "T", exp: true},
+		{input: "true", exp: true},
+		{input: "True", exp: true},
+		{input: "yes", exp: true},
+		{input: "YES", exp: true},
+		{input: "on", exp: true},
+		{input: "On", exp: true},
+		{input: "0", exp: false},
+		{input: "f", exp: false},
+		{input: "F", exp: false},
+		{input: "false", exp: false},
+		{input: "FALSE", exp: false},
+		{input: "no", exp: false},
+		{input: "No", exp: false},
+		{input: "off", exp: false},
+		{input: "OFF", exp: false},
+		{input: "maybe", expErr: core.ErrInvalidBool},
+		{input: "", expErr: core.ErrInvalidBool},
+	} {
+		t.Run(tc.input, func(t *core.T) {
+			val, err := core.ParseBoolExtended(tc.input)
+			t.AssertErrorIs(tc.expErr, err)
+			t.AssertEqual(tc.exp, val)
+		})
+	}
+}
+
+func TestParseFloat(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		name    string
+		bitSize int
+		input   string
+
+		exp    float64
+		expErr error
+	}{
+		{name: "Float", bitSize: 64, input: "4.2", exp: 4.2},
+		{name: "Exponent", bitSize: 64, input: "42e-1", exp: 4.2},
+		{name: "Overflow", bitSize: 32, input: "1e39", expErr: strconv.ErrRange},
+		{name: "Invalid", bitSize: 64, input: "4,2", expErr: strconv.ErrSyntax},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			val, err := core.ParseFloat(tc.bitSize)(tc.input)
+			t.AssertErrorIs(tc.expErr, err)
+			t.AssertEqual(tc.exp, val)
+		})
+	}
+}
+
+func TestParseHexBytes(s *testing.T) {
+	t := core.T{T: s}
+
+	val, err := core.ParseHexBytes("deadBEEF")
+	t.AssertErrorIs(nil, err)
+	t.AssertEqual([]byte{0xde, 0xad, 0xbe, 0xef}, val)
+
+	val, err = core.ParseHexBytes("dead0")
+	t.AssertErrorIs(hex.ErrLength, err)
+	t.AssertEqual(([]byte)(nil), val)
+
+	val, err = core.ParseHexBytes("deadzz")
+	var byteErr hex.InvalidByteError
+	t.AssertErrorAs(&byteErr, err)
+	t.Assert(strings.Contains(err.Error(), `"deadzz"`))
+	t.AssertEqual(([]byte)(nil), val)
+}
+
+func TestParseInt(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		name    string
+		base    int
+		bitSize int
+		input   string
+
+		exp    int8
+		expErr error
+	}{
+		{name: "Decimal", base: 10, bitSize: 8, input: "-42", exp: -42},
+		{name: "Hexadecimal", base: 16, bitSize: 8, input: "1f", exp: 31},
+		{name: "HexadecimalPrefix", base: 0, bitSize: 8, input: "0x1f", exp: 31},
+		{name: "Octal", base: 8, bitSize: 8, input: "17", exp: 15},
+		{name: "OctalPrefix", base: 0, bitSize: 8, input: "0o17", exp: 15},
+		{name: "Overflow", base: 10, bitSize: 8, input: "128", expErr: strconv.ErrRange},
+		{name: "OverflowType", base: 10, bitSize: 64, input: "300", expErr: strconv.ErrRange},
+		{name: "UnderflowType", base: 10, bitSize: 64, input: "-129", expErr: strconv.ErrRange},
+		{name: "Invalid", base: 10, bitSize: 8, input: "0x1f", expErr: strconv.ErrSyntax},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			val, err := core.ParseInt[int8](tc.base, tc.bitSize)(tc.input)
+			t.AssertErrorIs(tc.expErr, err)
+			t.AssertEqual(tc.exp, val)
+		})
+	}
+}
+
 func TestParseProtobufEnum(s *testing.T) {
-	t := &core.T{T: s, Options: cmp.Options{sortStrings}}
+	t := &core.T{T: s}
 
 	// That type and map emulate code generated by protoc.
 	type fakeEnum int32
@@ -178,6 +795,40 @@ func TestParseProtobufEnum(s *testing.T) {
 		}
 		t.AssertEqual(fakeEnum(0), val)
 	})
+
+	t.Run("UnknownValueMessage", func(t *core.T) {
+		for i := 0; i < 10; i++ {
+			_, err := parse("BAZ")
+			t.AssertEqual("unknown value BAZ, expected one of [BAR FAKE_UNKNOWN FOO]", err.Error())
+		}
+	})
+}
+
+func TestParseSlogLevel(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		input string
+
+		exp    slog.Level
+		expErr bool
+	}{
+		{input: "debug", exp: slog.LevelDebug},
+		{input: "DEBUG", exp: slog.LevelDebug},
+		{input: "info", exp: slog.LevelInfo},
+		{input: "Info", exp: slog.LevelInfo},
+		{input: "warn", exp: slog.LevelWarn},
+		{input: "error", exp: slog.LevelError},
+		{input: "warning", expErr: true},
+		{input: "", expErr: true},
+	} {
+		t.Run(tc.input, func(t *core.T) {
+			val, err := core.ParseSlogLevel(tc.input)
+			var enumErr core.UnknownEnumValueError[string]
+			t.AssertEqual(tc.expErr, errors.As(err, &enumErr))
+			t.AssertEqual(tc.exp, val)
+		})
+	}
 }
 
 func TestParseStringEnum(s *testing.T) {
@@ -247,6 +898,27 @@ func TestParseStringerEnum(s *testing.T) {
 	})
 }
 
+func TestParseTimeLayout(s *testing.T) {
+	t := &core.T{T: s}
+	parse := core.ParseTimeLayout("2006-01-02")
+
+	t.Run("Match", func(t *core.T) {
+		val, err := parse("2022-04-01")
+		t.AssertErrorIs(nil, err)
+		t.AssertEqual(time.Date(2022, time.April, 1, 0, 0, 0, 0, time.UTC), val)
+	})
+
+	t.Run("NoMatch", func(t *core.T) {
+		val, err := parse("01/04/2022")
+		var exp *time.ParseError
+		if t.AssertErrorAs(&exp, err) {
+			t.AssertEqual("01/04/2022", exp.Value)
+			t.AssertEqual("2006-01-02", exp.Layout)
+		}
+		t.AssertEqual(time.Time{}, val)
+	})
+}
+
 type fakeEnum struct{ string }
 
 var (
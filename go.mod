@@ -1,5 +1,9 @@
 module go.awhk.org/core
 
-go 1.18
+go 1.21
 
-require github.com/google/go-cmp v0.6.0
+require (
+	github.com/google/go-cmp v0.6.0
+	golang.org/x/sys v0.15.0
+	golang.org/x/time v0.5.0
+)
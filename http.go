@@ -4,11 +4,53 @@
 package core
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"math"
+	"mime"
+	"net"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
 )
 
+// AllowHeader returns a value suitable for an Allow header listing the
+// methods passed, sorted and comma-separated. It is what
+// FilterHTTPMethod and MethodMux use, and can be used to answer OPTIONS
+// requests consistently with them.
+func AllowHeader(methods ...string) string {
+	sorted := make([]string, len(methods))
+	copy(sorted, methods)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ", ")
+}
+
+// ContextHTTPHandler returns a handler that replaces the context of
+// requests with the one returned by fn before handing them over to the
+// passed handler. If fn returns nil, the context is left unchanged.
+// Values can then be read back with ContextValue.
+func ContextHTTPHandler(handler http.Handler, fn func(*http.Request) context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if ctx := fn(req); ctx != nil {
+			req = req.WithContext(ctx)
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// ContextValue returns the value associated with key in ctx, and
+// whether there was such a value of type T.
+func ContextValue[T any](ctx context.Context, key any) (T, bool) {
+	val, ok := ctx.Value(key).(T)
+	return val, ok
+}
+
 // FilteringHTTPHandler returns a handler that will check that a request
 // was not filtered before handing it over to the passed handler.
 func FilteringHTTPHandler(handler http.Handler, filters ...HTTPFilterFunc) http.Handler {
@@ -22,19 +64,134 @@ func FilteringHTTPHandler(handler http.Handler, filters ...HTTPFilterFunc) http.
 	})
 }
 
+// Filters returns a middleware that wraps handlers with
+// FilteringHTTPHandler and the filters passed, so that the same filters
+// can be applied to several handlers or chained with other middleware.
+func Filters(filters ...HTTPFilterFunc) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return FilteringHTTPHandler(handler, filters...)
+	}
+}
+
+// GzipHTTPHandler returns a handler that compresses responses with
+// gzip when the client accepts it and the response body is larger than
+// minSize bytes. Responses that already have a Content-Encoding, or
+// whose Content-Type denotes compressed data, e.g. images, are left
+// untouched. Calling Flush on the http.ResponseWriter forces the
+// decision to compress to be made, regardless of minSize.
+func GzipHTTPHandler(handler http.Handler, minSize int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(req.Header.Get("Accept-Encoding")) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize}
+		defer gw.close()
+		handler.ServeHTTP(gw, req)
+	})
+}
+
+// HeadersHTTPHandler returns a handler that sets the headers passed on
+// every response before handing requests over to the passed handler,
+// which can therefore still override them. Existing values of these
+// headers are replaced.
+func HeadersHTTPHandler(handler http.Handler, headers http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		h := w.Header()
+		for key, vals := range headers {
+			h[key] = append([]string(nil), vals...)
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
 // HTTPFilterFunc describes a filtering function for HTTP headers. The
 // filtering function must return true if a request should be filtered
-// and false otherwise. The filtering function may only call functions
-// on the http.ResponseWriter or change the http.Request if a request is
-// filtered.
+// and false otherwise. The filtering function may only write a response
+// or change the http.Request if a request is filtered, but it may set
+// headers on requests it lets through, as FilterETag does.
 type HTTPFilterFunc func(http.ResponseWriter, *http.Request) bool
 
+// FilterBasicAuth is an HTTPFilterFunc that filters requests that do
+// not carry HTTP basic authentication credentials accepted by verify.
+// Filtered requests get a 401 with a WWW-Authenticate header using the
+// realm passed. It is up to verify to compare credentials in constant
+// time.
+func FilterBasicAuth(realm string, verify func(user, pass string) bool) HTTPFilterFunc {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+	return func(w http.ResponseWriter, req *http.Request) bool {
+		if user, pass, ok := req.BasicAuth(); ok && verify(user, pass) {
+			return false
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+		w.WriteHeader(http.StatusUnauthorized)
+		return true
+	}
+}
+
+// FilterContentType is an HTTPFilterFunc that filters requests with a
+// body whose Content-Type header does not match any of the media types
+// passed, in which case a 415 is returned. Parameters such as charset
+// are ignored, and media types are compared case-insensitively. GET and
+// HEAD requests, as well as requests known to have an empty body, are
+// never filtered.
+func FilterContentType(types ...string) HTTPFilterFunc {
+	allowed := make(map[string]bool, len(types))
+	for _, typ := range types {
+		allowed[strings.ToLower(typ)] = true
+	}
+	return func(w http.ResponseWriter, req *http.Request) bool {
+		if req.Method == http.MethodGet || req.Method == http.MethodHead || req.ContentLength == 0 {
+			return false
+		}
+		if typ, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil && allowed[typ] {
+			return false
+		}
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return true
+	}
+}
+
+// FilterETag is an HTTPFilterFunc that sets the ETag header of every
+// response to etag, which should include quotes. Requests with an
+// If-None-Match header matching etag are filtered with a 304. Weak
+// comparison is used, as required for If-None-Match, so ‘W/"tag"’ and
+// ‘"tag"’ match each other. ‘*’ matches any ETag.
+func FilterETag(etag string) HTTPFilterFunc {
+	tag := strings.TrimPrefix(etag, "W/")
+	return func(w http.ResponseWriter, req *http.Request) bool {
+		w.Header().Set("ETag", etag)
+		for _, candidate := range strings.Split(req.Header.Get("If-None-Match"), ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+				w.WriteHeader(http.StatusNotModified)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterFeature is an HTTPFilterFunc that filters requests while the
+// feature passed is disabled, in which case a 404 is returned. Since
+// the feature is checked on every request, it can be toggled at run
+// time.
+func FilterFeature(f *Feature) HTTPFilterFunc {
+	return func(w http.ResponseWriter, _ *http.Request) bool {
+		if f.Enabled() {
+			return false
+		}
+		w.WriteHeader(http.StatusNotFound)
+		return true
+	}
+}
+
 // FilterHTTPMethod is an HTTPFilterFunc that filters requests based on
 // the HTTP methods passed. Requests that do not have a matching method
 // will be filtered.
 func FilterHTTPMethod(methods ...string) HTTPFilterFunc {
-	sort.Strings(methods)
-	allowed := strings.Join(methods, ", ")
+	allowed := AllowHeader(methods...)
 	return func(w http.ResponseWriter, req *http.Request) bool {
 		for _, method := range methods {
 			if method == req.Method {
@@ -46,3 +203,390 @@ func FilterHTTPMethod(methods ...string) HTTPFilterFunc {
 		return true
 	}
 }
+
+// FilterHTTPS is an HTTPFilterFunc that filters requests that were not
+// made over HTTPS with a 400. If trustForwardedHeader is true, the
+// X-Forwarded-Proto header set by the closest proxy is used to tell
+// which scheme the client used. That header can be forged by clients,
+// so it should only be trusted behind a proxy that sets it.
+func FilterHTTPS(trustForwardedHeader bool) HTTPFilterFunc {
+	return func(w http.ResponseWriter, req *http.Request) bool {
+		if req.TLS != nil {
+			return false
+		}
+		if trustForwardedHeader {
+			protos := strings.Split(req.Header.Get("X-Forwarded-Proto"), ",")
+			if strings.EqualFold(strings.TrimSpace(protos[len(protos)-1]), "https") {
+				return false
+			}
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return true
+	}
+}
+
+// FilterMaxBodySize is an HTTPFilterFunc that filters requests with a
+// body larger than limit bytes. Requests announcing a larger body are
+// filtered right away; other requests have their body limited so that
+// reading more than limit bytes fails, even if the Content-Length
+// header was missing or wrong.
+func FilterMaxBodySize(limit int64) HTTPFilterFunc {
+	return func(w http.ResponseWriter, req *http.Request) bool {
+		if req.ContentLength > limit {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return true
+		}
+		req.Body = http.MaxBytesReader(w, req.Body, limit)
+		return false
+	}
+}
+
+// FilterRateLimit is an HTTPFilterFunc that filters requests exceeding
+// a rate of rps requests per second, with bursts of up to burst
+// requests. A single token bucket is shared by all requests. Filtered
+// requests get a 429 with a Retry-After header telling when the next
+// request could be allowed, if ever.
+func FilterRateLimit(rps float64, burst int) HTTPFilterFunc {
+	lim := rate.NewLimiter(rate.Limit(rps), burst)
+	return func(w http.ResponseWriter, _ *http.Request) bool {
+		r := lim.Reserve()
+		if r.OK() {
+			delay := r.Delay()
+			if delay == 0 {
+				return false
+			}
+			r.Cancel()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		return true
+	}
+}
+
+// MethodMux returns a handler that dispatches requests to the handler
+// registered for their HTTP method. HEAD requests are handled by the
+// GET handler if there is no HEAD handler. Requests with any other
+// method get a 405 with an appropriate Allow header.
+func MethodMux(handlers map[string]http.Handler) http.Handler {
+	mux := make(map[string]http.Handler, len(handlers)+1)
+	for method, handler := range handlers {
+		mux[method] = handler
+	}
+	if _, found := mux[http.MethodHead]; !found {
+		if handler, found := mux[http.MethodGet]; found {
+			mux[http.MethodHead] = handler
+		}
+	}
+	allowed := AllowHeader(MapKeys(mux)...)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if handler, found := mux[req.Method]; found {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		w.Header().Set("Allow", allowed)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+}
+
+// TimeoutHTTPHandler returns a handler that replaces the context of
+// requests with one that is canceled after d. If the passed handler has
+// not started writing a response by then, a 503 is returned to the
+// client and later writes fail with http.ErrHandlerTimeout. Otherwise,
+// the handler is left to complete its response.
+//
+// The status is always 503, like with http.TimeoutHandler. Handlers
+// needing another status should enforce a shorter deadline of their own
+// and respond before d elapses.
+//
+// The http.ResponseWriter passed to the handler implements http.Flusher,
+// and Unwrap so that http.ResponseController can reach the underlying
+// http.ResponseWriter.
+func TimeoutHTTPHandler(handler http.Handler, d time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), d)
+		defer cancel()
+
+		var (
+			done = make(chan any, 1)
+			tw   = &timeoutResponseWriter{ctx: ctx, header: http.Header{}, w: w}
+		)
+		go func() {
+			defer func() { done <- recover() }()
+			handler.ServeHTTP(tw, req.WithContext(ctx))
+		}()
+
+		select {
+		case p := <-done:
+			if p != nil {
+				panic(p)
+			}
+			tw.finish()
+			return
+		case <-ctx.Done():
+		}
+
+		tw.mu.Lock()
+		if tw.wroteHeader {
+			tw.mu.Unlock()
+			if p := <-done; p != nil {
+				panic(p)
+			}
+			return
+		}
+		tw.timedOut = true
+		tw.mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+}
+
+// ServeHTTP serves HTTP requests on the passed net.Listener until ctx
+// is canceled, at which point srv is shut down gracefully. It works
+// like ServeHTTPGrace with a grace period of 10 seconds.
+func ServeHTTP(ctx context.Context, srv *http.Server, l net.Listener) error {
+	return ServeHTTPGrace(ctx, srv, l, 10*time.Second)
+}
+
+// ServeHTTPGrace serves HTTP requests on the passed net.Listener until
+// ctx is canceled, at which point srv is shut down gracefully.
+// Connections that are still active after grace are closed, and
+// context.DeadlineExceeded is returned.
+//
+// ServeHTTPGrace returns nil after a clean shutdown, or the error
+// returned by srv.Serve if it stopped for another reason.
+func ServeHTTPGrace(ctx context.Context, srv *http.Server, l net.Listener, grace time.Duration) error {
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Serve(l) }()
+
+	select {
+	case err := <-errs:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close()
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+	minSize int
+	status  int
+}
+
+var _ http.Flusher = &gzipResponseWriter{}
+
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.start(true)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) > w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) close() error {
+	if !w.decided {
+		return w.start(false)
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+func (w *gzipResponseWriter) start(compress bool) (err error) {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if compress && h.Get("Content-Encoding") == "" && !isCompressedContentType(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		if w.gz != nil {
+			_, err = w.gz.Write(w.buf)
+		} else {
+			_, err = w.ResponseWriter.Write(w.buf)
+		}
+	}
+	w.buf = nil
+	return err
+}
+
+type timeoutResponseWriter struct {
+	ctx    context.Context
+	header http.Header
+	w      http.ResponseWriter
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+var _ http.Flusher = &timeoutResponseWriter{}
+
+// Flush writes the response header if needed, then flushes the
+// underlying http.ResponseWriter if it supports it. Nothing happens
+// once the deadline has passed.
+func (w *timeoutResponseWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.writeHeaderLocked(http.StatusOK) {
+		return
+	}
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *timeoutResponseWriter) Header() http.Header { return w.header }
+
+func (w *timeoutResponseWriter) Unwrap() http.ResponseWriter { return w.w }
+
+func (w *timeoutResponseWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.writeHeaderLocked(http.StatusOK) {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.w.Write(b)
+}
+
+func (w *timeoutResponseWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writeHeaderLocked(status)
+}
+
+// finish writes the response header with an implicit 200 if the handler
+// returned without writing anything, so that the headers it set are not
+// lost. A 503 is written instead if the handler hit the deadline.
+func (w *timeoutResponseWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch {
+	case w.wroteHeader:
+	case w.timedOut:
+		w.w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		for k, v := range w.header {
+			w.w.Header()[k] = v
+		}
+		w.w.WriteHeader(http.StatusOK)
+		w.wroteHeader = true
+	}
+}
+
+// writeHeaderLocked writes the response header if that has not been
+// done already, and reports whether the response can still be written.
+// Nothing can be written once the deadline has passed, even if the
+// handler was quicker to react than TimeoutHTTPHandler.
+func (w *timeoutResponseWriter) writeHeaderLocked(status int) bool {
+	if w.wroteHeader {
+		return true
+	}
+	if w.timedOut || w.ctx.Err() != nil {
+		w.timedOut = true
+		return false
+	}
+	for k, v := range w.header {
+		w.w.Header()[k] = v
+	}
+	w.w.WriteHeader(status)
+	w.wroteHeader = true
+	return true
+}
+
+func acceptsGzip(header string) bool {
+	for _, enc := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(enc, ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		params = strings.TrimSpace(params)
+		if !strings.HasPrefix(params, "q=") {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+func isCompressedContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	switch {
+	case mediaType == "image/svg+xml":
+		return false
+	case strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "video/"):
+		return true
+	}
+	switch mediaType {
+	case "application/gzip",
+		"application/x-bzip2",
+		"application/x-gzip",
+		"application/x-xz",
+		"application/zip",
+		"application/zstd",
+		"font/woff",
+		"font/woff2":
+		return true
+	}
+	return false
+}
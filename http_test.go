@@ -4,13 +4,59 @@
 package core_test
 
 import (
+	"compress/gzip"
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"go.awhk.org/core"
 )
 
+func TestAllowHeader(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual("", core.AllowHeader())
+	t.AssertEqual("GET", core.AllowHeader(http.MethodGet))
+
+	methods := []string{http.MethodPost, http.MethodGet, http.MethodDelete}
+	t.AssertEqual("DELETE, GET, POST", core.AllowHeader(methods...))
+	t.AssertEqual([]string{http.MethodPost, http.MethodGet, http.MethodDelete}, methods)
+}
+
+func TestContextHTTPHandler(s *testing.T) {
+	t := core.T{T: s}
+
+	type key struct{}
+	var (
+		actual string
+		found  bool
+	)
+	inner := http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		actual, found = core.ContextValue[string](req.Context(), key{})
+	})
+
+	t.Run("Success", func(t *core.T) {
+		handler := core.ContextHTTPHandler(inner, func(req *http.Request) context.Context {
+			return context.WithValue(req.Context(), key{}, "some-id")
+		})
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		t.AssertEqual(true, found)
+		t.AssertEqual("some-id", actual)
+	})
+
+	t.Run("WhenNil", func(t *core.T) {
+		handler := core.ContextHTTPHandler(inner, func(*http.Request) context.Context { return nil })
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		t.AssertEqual(false, found)
+		t.AssertEqual("", actual)
+	})
+}
+
 func TestFilteringHTTPHandler(s *testing.T) {
 	t := core.T{T: s}
 
@@ -54,6 +100,239 @@ func TestFilteringHTTPHandler(s *testing.T) {
 	}
 }
 
+func TestFilters(s *testing.T) {
+	t := core.T{T: s}
+
+	middleware := core.Filters(core.FilterHTTPMethod(http.MethodGet))
+	for _, body := range []string{"foo", "bar"} {
+		body := body
+		handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			io.WriteString(w, body)
+		}))
+		t.Run(body, func(t *core.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			t.AssertEqual(http.StatusOK, w.Code)
+			t.AssertEqual(body, w.Body.String())
+
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+			t.AssertEqual(http.StatusMethodNotAllowed, w.Code)
+			t.AssertEqual("", w.Body.String())
+		})
+	}
+}
+
+func TestFilterBasicAuth(s *testing.T) {
+	t := core.T{T: s}
+
+	filter := core.FilterBasicAuth("test", func(user, pass string) bool {
+		return user == "user" && pass == "pass"
+	})
+	for _, tc := range []struct {
+		name string
+		user string
+		pass string
+
+		expAuthenticate string
+		expFiltered     bool
+		expStatusCode   int
+	}{
+		{
+			name: "Success",
+			user: "user",
+			pass: "pass",
+
+			expFiltered:   false,
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name: "WhenInvalid",
+			user: "user",
+			pass: "password",
+
+			expAuthenticate: `Basic realm="test", charset="UTF-8"`,
+			expFiltered:     true,
+			expStatusCode:   http.StatusUnauthorized,
+		},
+		{
+			name: "WhenMissing",
+
+			expAuthenticate: `Basic realm="test", charset="UTF-8"`,
+			expFiltered:     true,
+			expStatusCode:   http.StatusUnauthorized,
+		},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			var (
+				req = httptest.NewRequest(http.MethodGet, "/", nil)
+				w   = httptest.NewRecorder()
+			)
+			if tc.user != "" {
+				req.SetBasicAuth(tc.user, tc.pass)
+			}
+			t.AssertEqual(tc.expFiltered, filter(w, req))
+
+			res := w.Result()
+			t.AssertEqual(tc.expAuthenticate, res.Header.Get("WWW-Authenticate"))
+			t.AssertEqual(tc.expStatusCode, res.StatusCode)
+		})
+	}
+}
+
+func TestFilterContentType(s *testing.T) {
+	t := core.T{T: s}
+
+	filter := core.FilterContentType("application/json")
+	for _, tc := range []struct {
+		name        string
+		method      string
+		body        string
+		contentType string
+
+		expFiltered   bool
+		expStatusCode int
+	}{
+		{
+			name:        "Success",
+			method:      http.MethodPost,
+			body:        "{}",
+			contentType: "Application/JSON; charset=utf-8",
+
+			expFiltered:   false,
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name:        "WhenMismatching",
+			method:      http.MethodPost,
+			body:        "{}",
+			contentType: "text/plain",
+
+			expFiltered:   true,
+			expStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "WhenMissing",
+			method: http.MethodPut,
+			body:   "{}",
+
+			expFiltered:   true,
+			expStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "WhenBodyless",
+			method:      http.MethodGet,
+			contentType: "text/plain",
+
+			expFiltered:   false,
+			expStatusCode: http.StatusOK,
+		},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			var (
+				req = httptest.NewRequest(tc.method, "/", strings.NewReader(tc.body))
+				w   = httptest.NewRecorder()
+			)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			t.AssertEqual(tc.expFiltered, filter(w, req))
+			t.AssertEqual(tc.expStatusCode, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestFilterETag(s *testing.T) {
+	t := core.T{T: s}
+
+	filter := core.FilterETag(`"42"`)
+	for _, tc := range []struct {
+		name        string
+		ifNoneMatch string
+
+		expFiltered   bool
+		expStatusCode int
+	}{
+		{
+			name:        "Match",
+			ifNoneMatch: `"42"`,
+
+			expFiltered:   true,
+			expStatusCode: http.StatusNotModified,
+		},
+		{
+			name:        "MatchWeak",
+			ifNoneMatch: `W/"42"`,
+
+			expFiltered:   true,
+			expStatusCode: http.StatusNotModified,
+		},
+		{
+			name:        "MatchList",
+			ifNoneMatch: `"21", "42"`,
+
+			expFiltered:   true,
+			expStatusCode: http.StatusNotModified,
+		},
+		{
+			name:        "MatchAny",
+			ifNoneMatch: "*",
+
+			expFiltered:   true,
+			expStatusCode: http.StatusNotModified,
+		},
+		{
+			name:        "Mismatch",
+			ifNoneMatch: `"21"`,
+
+			expFiltered:   false,
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name: "NoHeader",
+
+			expFiltered:   false,
+			expStatusCode: http.StatusOK,
+		},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			var (
+				req = httptest.NewRequest(http.MethodGet, "/", nil)
+				w   = httptest.NewRecorder()
+			)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+			t.AssertEqual(tc.expFiltered, filter(w, req))
+
+			res := w.Result()
+			t.AssertEqual(`"42"`, res.Header.Get("ETag"))
+			t.AssertEqual(tc.expStatusCode, res.StatusCode)
+		})
+	}
+}
+
+func TestFilterFeature(s *testing.T) {
+	t := core.T{T: s}
+
+	var f core.Feature
+	filter := core.FilterFeature(&f)
+
+	w := httptest.NewRecorder()
+	t.AssertEqual(true, filter(w, httptest.NewRequest(http.MethodGet, "/", nil)))
+	t.AssertEqual(http.StatusNotFound, w.Result().StatusCode)
+
+	f.Enable()
+	w = httptest.NewRecorder()
+	t.AssertEqual(false, filter(w, httptest.NewRequest(http.MethodGet, "/", nil)))
+	t.AssertEqual(http.StatusOK, w.Result().StatusCode)
+
+	f.Disable()
+	w = httptest.NewRecorder()
+	t.AssertEqual(true, filter(w, httptest.NewRequest(http.MethodGet, "/", nil)))
+	t.AssertEqual(http.StatusNotFound, w.Result().StatusCode)
+}
+
 func TestFilterHTTPMethod(s *testing.T) {
 	t := core.T{T: s}
 
@@ -95,3 +374,452 @@ func TestFilterHTTPMethod(s *testing.T) {
 		})
 	}
 }
+
+func TestFilterHTTPS(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		name      string
+		target    string
+		forwarded string
+		trust     bool
+
+		expFiltered   bool
+		expStatusCode int
+	}{
+		{
+			name:   "TLS",
+			target: "https://example.com/",
+
+			expFiltered:   false,
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name:      "Forwarded",
+			target:    "http://example.com/",
+			forwarded: "https",
+			trust:     true,
+
+			expFiltered:   false,
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name:      "ForwardedTwice",
+			target:    "http://example.com/",
+			forwarded: "http, https",
+			trust:     true,
+
+			expFiltered:   false,
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name:      "WhenForwardedNotTrusted",
+			target:    "http://example.com/",
+			forwarded: "https",
+
+			expFiltered:   true,
+			expStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:      "WhenForwardedHTTP",
+			target:    "http://example.com/",
+			forwarded: "http",
+			trust:     true,
+
+			expFiltered:   true,
+			expStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "WhenPlain",
+			target: "http://example.com/",
+			trust:  true,
+
+			expFiltered:   true,
+			expStatusCode: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			var (
+				req = httptest.NewRequest(http.MethodGet, tc.target, nil)
+				w   = httptest.NewRecorder()
+			)
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-Proto", tc.forwarded)
+			}
+			t.AssertEqual(tc.expFiltered, core.FilterHTTPS(tc.trust)(w, req))
+			t.AssertEqual(tc.expStatusCode, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestFilterMaxBodySize(s *testing.T) {
+	t := core.T{T: s}
+
+	filter := core.FilterMaxBodySize(4)
+
+	t.Run("Success", func(t *core.T) {
+		var (
+			req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("1234"))
+			w   = httptest.NewRecorder()
+		)
+		t.AssertEqual(false, filter(w, req))
+		body, err := io.ReadAll(req.Body)
+		t.AssertErrorIs(nil, err)
+		t.AssertEqual("1234", string(body))
+	})
+
+	t.Run("WhenContentLengthTooLarge", func(t *core.T) {
+		var (
+			req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345"))
+			w   = httptest.NewRecorder()
+		)
+		t.AssertEqual(true, filter(w, req))
+		t.AssertEqual(http.StatusRequestEntityTooLarge, w.Result().StatusCode)
+	})
+
+	t.Run("WhenBodyTooLarge", func(t *core.T) {
+		var (
+			req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345"))
+			w   = httptest.NewRecorder()
+		)
+		req.ContentLength = -1
+		t.AssertEqual(false, filter(w, req))
+		_, err := io.ReadAll(req.Body)
+		t.AssertNotEqual(nil, err)
+	})
+}
+
+func TestFilterRateLimit(s *testing.T) {
+	t := core.T{T: s}
+
+	filter := core.FilterRateLimit(0.5, 2)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		t.AssertEqual(false, filter(w, httptest.NewRequest(http.MethodGet, "/", nil)))
+		t.AssertEqual(http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	t.AssertEqual(true, filter(w, httptest.NewRequest(http.MethodGet, "/", nil)))
+	t.AssertEqual(http.StatusTooManyRequests, w.Code)
+	t.AssertEqual("2", w.Header().Get("Retry-After"))
+
+	t.Run("WhenNoBurst", func(t *core.T) {
+		filter := core.FilterRateLimit(1, 0)
+		w := httptest.NewRecorder()
+		t.AssertEqual(true, filter(w, httptest.NewRequest(http.MethodGet, "/", nil)))
+		t.AssertEqual(http.StatusTooManyRequests, w.Code)
+		t.AssertEqual("", w.Header().Get("Retry-After"))
+	})
+}
+
+func TestGzipHTTPHandler(s *testing.T) {
+	t := core.T{T: s}
+
+	var (
+		large = strings.Repeat("Hello World! ", 100)
+		small = "Hello World!"
+	)
+	for _, tc := range []struct {
+		name           string
+		acceptEncoding string
+		body           string
+		contentType    string
+		flush          bool
+
+		expContentEncoding string
+	}{
+		{
+			name:           "Compressed",
+			acceptEncoding: "deflate, gzip",
+			body:           large,
+
+			expContentEncoding: "gzip",
+		},
+		{
+			name:           "CompressedWhenFlushed",
+			acceptEncoding: "gzip",
+			body:           small,
+			flush:          true,
+
+			expContentEncoding: "gzip",
+		},
+		{
+			name:           "WhenTooSmall",
+			acceptEncoding: "gzip",
+			body:           small,
+		},
+		{
+			name: "WhenNotAccepted",
+			body: large,
+		},
+		{
+			name:           "WhenRefused",
+			acceptEncoding: "gzip;q=0",
+			body:           large,
+		},
+		{
+			name:           "WhenAlreadyCompressed",
+			acceptEncoding: "gzip",
+			body:           large,
+			contentType:    "image/png",
+		},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			handler := core.GzipHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tc.contentType != "" {
+					w.Header().Set("Content-Type", tc.contentType)
+				}
+				w.WriteHeader(http.StatusAccepted)
+				io.WriteString(w, tc.body[:len(tc.body)/2])
+				if tc.flush {
+					w.(http.Flusher).Flush()
+				}
+				io.WriteString(w, tc.body[len(tc.body)/2:])
+			}), 256)
+			var (
+				req = httptest.NewRequest(http.MethodGet, "/", nil)
+				w   = httptest.NewRecorder()
+			)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			handler.ServeHTTP(w, req)
+
+			res := w.Result()
+			t.AssertEqual(http.StatusAccepted, res.StatusCode)
+			t.AssertEqual(tc.expContentEncoding, res.Header.Get("Content-Encoding"))
+			t.AssertEqual("Accept-Encoding", res.Header.Get("Vary"))
+			t.AssertEqual(tc.flush, w.Flushed)
+			body := res.Body
+			if tc.expContentEncoding == "gzip" {
+				var err error
+				body, err = gzip.NewReader(res.Body)
+				t.Must(t.AssertErrorIs(nil, err))
+			}
+			buf, err := io.ReadAll(body)
+			t.AssertErrorIs(nil, err)
+			t.AssertEqual(tc.body, string(buf))
+		})
+	}
+}
+
+func TestHeadersHTTPHandler(s *testing.T) {
+	t := core.T{T: s}
+
+	headers := http.Header{
+		"Cache-Control":          {"no-store"},
+		"X-Content-Type-Options": {"nosniff"},
+	}
+	handler := core.HeadersHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/override" {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		w.Header().Add("X-Content-Type-Options", "other")
+	}), headers)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	t.AssertEqual("no-store", w.Header().Get("Cache-Control"))
+	t.AssertEqual([]string{"nosniff", "other"}, w.Header().Values("X-Content-Type-Options"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/override", nil))
+	t.AssertEqual("max-age=60", w.Header().Get("Cache-Control"))
+
+	t.AssertEqual([]string{"nosniff"}, headers.Values("X-Content-Type-Options"))
+}
+
+func TestMethodMux(s *testing.T) {
+	t := core.T{T: s}
+
+	handler := core.MethodMux(map[string]http.Handler{
+		http.MethodGet: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+		http.MethodPost: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}),
+	})
+	for _, tc := range []struct {
+		name   string
+		method string
+
+		expAllow      string
+		expStatusCode int
+	}{
+		{
+			name:   "Get",
+			method: http.MethodGet,
+
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name:   "Post",
+			method: http.MethodPost,
+
+			expStatusCode: http.StatusCreated,
+		},
+		{
+			name:   "HeadFallback",
+			method: http.MethodHead,
+
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name:   "WhenNotAllowed",
+			method: http.MethodDelete,
+
+			expAllow:      "GET, HEAD, POST",
+			expStatusCode: http.StatusMethodNotAllowed,
+		},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			var (
+				req = httptest.NewRequest(tc.method, "/", nil)
+				w   = httptest.NewRecorder()
+			)
+			handler.ServeHTTP(w, req)
+
+			res := w.Result()
+			t.AssertEqual(tc.expAllow, res.Header.Get("Allow"))
+			t.AssertEqual(tc.expStatusCode, res.StatusCode)
+		})
+	}
+}
+
+func TestTimeoutHTTPHandler(s *testing.T) {
+	t := core.T{T: s}
+
+	t.Run("Success", func(t *core.T) {
+		handler := core.TimeoutHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, "Hello World!")
+		}), time.Second)
+		var (
+			req = httptest.NewRequest(http.MethodGet, "/", nil)
+			w   = httptest.NewRecorder()
+		)
+		handler.ServeHTTP(w, req)
+
+		res := w.Result()
+		t.AssertEqual(http.StatusAccepted, res.StatusCode)
+		t.AssertEqual("text/plain", res.Header.Get("Content-Type"))
+		t.AssertEqual("Hello World!", w.Body.String())
+	})
+
+	t.Run("Flush", func(t *core.T) {
+		flushed := make(chan bool, 1)
+		handler := core.TimeoutHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			io.WriteString(w, "Hello")
+			flushed <- http.NewResponseController(w).Flush() == nil
+		}), time.Second)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		t.AssertEqual(true, <-flushed)
+		t.AssertEqual(true, w.Flushed)
+		t.AssertEqual("Hello", w.Body.String())
+	})
+
+	t.Run("WhenNothingWritten", func(t *core.T) {
+		handler := core.TimeoutHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("X-Foo", "bar")
+		}), time.Second)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		res := w.Result()
+		t.AssertEqual(http.StatusOK, res.StatusCode)
+		t.AssertEqual("bar", res.Header.Get("X-Foo"))
+	})
+
+	t.Run("WhenTimedOut", func(t *core.T) {
+		errs := make(chan error, 2)
+		handler := core.TimeoutHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+			errs <- req.Context().Err()
+			_, err := io.WriteString(w, "Hello World!")
+			errs <- err
+		}), 10*time.Millisecond)
+		var (
+			req = httptest.NewRequest(http.MethodGet, "/", nil)
+			w   = httptest.NewRecorder()
+		)
+		handler.ServeHTTP(w, req)
+
+		res := w.Result()
+		t.AssertEqual(http.StatusServiceUnavailable, res.StatusCode)
+		t.AssertErrorIs(context.DeadlineExceeded, <-errs)
+		t.AssertErrorIs(http.ErrHandlerTimeout, <-errs)
+		t.AssertEqual("", w.Body.String())
+	})
+}
+
+func TestServeHTTP(s *testing.T) {
+	t := core.T{T: s}
+
+	t.Run("Success", func(t *core.T) {
+		var (
+			ctx, cancel = context.WithCancel(context.Background())
+			p           = core.ListenPipe()
+			srv         = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})}
+		)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() { done <- core.ServeHTTP(ctx, srv, p) }()
+
+		client := &http.Client{Transport: &http.Transport{DialContext: p.DialContext}}
+		res, err := client.Get("http://pipe/")
+		if t.AssertErrorIs(nil, err) {
+			res.Body.Close()
+			t.AssertEqual(http.StatusNoContent, res.StatusCode)
+		}
+
+		cancel()
+		t.AssertErrorIs(nil, <-done)
+	})
+
+	t.Run("WhenGraceExceeded", func(t *core.T) {
+		var (
+			ctx, cancel = context.WithCancel(context.Background())
+			p           = core.ListenPipe()
+			started     = make(chan struct{})
+			release     = make(chan struct{})
+			srv         = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				close(started)
+				<-release
+			})}
+		)
+		defer cancel()
+		defer close(release)
+
+		done := make(chan error, 1)
+		go func() { done <- core.ServeHTTPGrace(ctx, srv, p, 10*time.Millisecond) }()
+
+		errs := make(chan error, 1)
+		go func() {
+			client := &http.Client{Transport: &http.Transport{DialContext: p.DialContext}}
+			res, err := client.Get("http://pipe/")
+			if err == nil {
+				res.Body.Close()
+			}
+			errs <- err
+		}()
+
+		<-started
+		cancel()
+		t.AssertErrorIs(context.DeadlineExceeded, <-done)
+		t.AssertNotNil(<-errs)
+	})
+
+	t.Run("WhenServeFails", func(t *core.T) {
+		p := core.ListenPipe()
+		p.Close()
+
+		t.AssertErrorIs(syscall.EINVAL, core.ServeHTTP(context.Background(), &http.Server{}, p))
+	})
+}
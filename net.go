@@ -5,33 +5,189 @@ package core
 
 import (
 	"context"
+	"errors"
 	"net"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 )
 
-// Listen is a wrapper around net.Listen. If addr cannot be split in two
-// parts around the first colon found, Listen will try to create a UNIX
-// or TCP net.Listener depending on whether addr contains a slash.
+// ErrReusePortUnsupported is returned by ListenReusePort on platforms
+// that do not support SO_REUSEPORT.
+var ErrReusePortUnsupported = errors.New("SO_REUSEPORT is not supported on this platform")
+
+// Listen is a wrapper around net.Listen. The network used is derived
+// from addr by ResolveAddr.
+//
+// On Unix systems, the standard library already sets SO_REUSEADDR on
+// TCP listeners, so a port can be bound again right after a listener
+// is closed, even if connections are lingering in TIME_WAIT.
 func Listen(addr string) (net.Listener, error) {
-	if fields := strings.SplitN(addr, ":", 2); len(fields) == 2 {
-		return net.Listen(fields[0], fields[1])
+	return net.Listen(ResolveAddr(addr))
+}
+
+// ListenAll calls Listen for each of the addresses passed, and returns
+// the listeners in the same order. If any call fails, the listeners
+// already created are closed before the error is returned.
+func ListenAll(addrs ...string) ([]net.Listener, error) {
+	ls := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		l, err := Listen(addr)
+		if err != nil {
+			for _, l := range ls {
+				l.Close()
+			}
+			return nil, err
+		}
+		ls = append(ls, l)
 	}
-	if strings.ContainsRune(addr, '/') {
-		return net.Listen("unix", addr)
+	return ls, nil
+}
+
+// ListenPacket is a wrapper around net.ListenPacket. The network used
+// is derived from addr like ResolveAddr does, except it defaults to
+// ‘unixgram’ if addr contains a slash, and ‘udp’ if it does not.
+func ListenPacket(addr string) (net.PacketConn, error) {
+	return net.ListenPacket(resolveAddr(addr, "udp", "unixgram"))
+}
+
+// ListenReusePort works like Listen, except SO_REUSEADDR and
+// SO_REUSEPORT are set on the socket before it is bound, so that
+// several processes can listen on the same port. It returns an error
+// wrapping ErrReusePortUnsupported on platforms lacking SO_REUSEPORT.
+func ListenReusePort(addr string) (net.Listener, error) {
+	network, address := ResolveAddr(addr)
+	lc := net.ListenConfig{Control: controlReusePort}
+	return lc.Listen(context.Background(), network, address)
+}
+
+// ResolveAddr splits addr into a network and an address suitable for
+// net.Listen or net.Dial. If addr is prefixed with a network and a
+// colon, e.g. ‘tcp6:[::1]:80’ or ‘unix:/run/app.sock’, that network is
+// used. Otherwise, the network is ‘unix’ if addr contains a slash, and
+// ‘tcp’ if it does not.
+func ResolveAddr(addr string) (network, address string) {
+	return resolveAddr(addr, "tcp", "unix")
+}
+
+// DialRetry dials addr, which is interpreted the same way Listen does.
+// If the connection is refused, DialRetry waits for backoff before
+// trying again, up to attempts times in total or until ctx is canceled.
+// The last error encountered is returned if no connection could be
+// established.
+func DialRetry(ctx context.Context, addr string, attempts int, backoff time.Duration) (net.Conn, error) {
+	var (
+		d                = net.Dialer{}
+		network, address = ResolveAddr(addr)
+	)
+	for i := 1; ; i++ {
+		conn, err := d.DialContext(ctx, network, address)
+		if !errors.Is(err, syscall.ECONNREFUSED) || i >= attempts {
+			return conn, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// FreePort returns a TCP port that was free on the loopback interface
+// when FreePort was called. This is inherently racy, as another process
+// may bind the port before the caller does, so FreePort should only be
+// used when a port number must be handed to something that cannot be
+// passed a listener, e.g. an external server started by a test.
+func FreePort() (int, error) {
+	l, err := Listen("tcp:127.0.0.1:0")
+	if err != nil {
+		return 0, err
 	}
-	return net.Listen("tcp", addr)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// IdleTimeoutConn returns a net.Conn that closes conn once no data has
+// been read from or written to it for idle. Reads and writes blocked at
+// that point fail like they would on any closed connection.
+func IdleTimeoutConn(conn net.Conn, idle time.Duration) net.Conn {
+	return &idleTimeoutConn{Conn: conn, idle: idle, timer: time.AfterFunc(idle, func() { conn.Close() })}
+}
+
+// LimitListener returns a net.Listener that accepts at most n
+// connections from l at once. Once n connections are open, Accept
+// blocks until one of them is closed, or until the listener is.
+func LimitListener(l net.Listener, n int) net.Listener {
+	return &limitListener{Listener: l, sem: make(chan struct{}, n), done: make(chan struct{})}
+}
+
+// ContextDialer is implemented by types that can dial connections with
+// a context, e.g. net.Dialer, DialerRegistry, and PipeListener. Its
+// method matches the signature expected by http.Transport.DialContext
+// and similar fields.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+var (
+	_ ContextDialer = &net.Dialer{}
+	_ ContextDialer = &DialerRegistry{}
+	_ ContextDialer = &PipeListener{}
+)
+
+// DialerRegistry routes connections to dialing functions registered
+// for specific addresses, falling back to a net.Dialer for other
+// addresses. This is useful to make code connect to a PipeListener
+// without it knowing, e.g. in tests.
+//
+// DialerRegistry must not be copied after its first use.
+type DialerRegistry struct {
+	dialer net.Dialer
+	dials  map[string]func(context.Context, string, string) (net.Conn, error)
+	mu     sync.RWMutex
+}
+
+// Register registers dial for addr. Subsequent calls to DialContext
+// for addr will use dial, regardless of the network.
+func (r *DialerRegistry) Register(addr string, dial func(context.Context, string, string) (net.Conn, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.dials == nil {
+		r.dials = map[string]func(context.Context, string, string) (net.Conn, error){}
+	}
+	r.dials[addr] = dial
+}
+
+func (r *DialerRegistry) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	r.mu.RLock()
+	dial, found := r.dials[addr]
+	r.mu.RUnlock()
+
+	if found {
+		return dial(ctx, network, addr)
+	}
+	return r.dialer.DialContext(ctx, network, addr)
 }
 
 // PipeListener is a net.Listener that works over a pipe. It provides
 // dialer functions that can be used in an HTTP client or gRPC options.
 //
+// PipeListener keeps track of the connections it creates until they are
+// closed. Close only stops new connections from being made, whereas
+// CloseConns closes both ends of every connection still open.
+//
 // PipeListener must not be copied after its first use.
 type PipeListener struct {
-	closed int32
-	conns  chan net.Conn
-	done   chan struct{}
+	closed  int32
+	conns   chan net.Conn
+	done    chan struct{}
+	mu      sync.Mutex
+	tracked map[*pipeConn]struct{}
 
 	_ NoCopy
 }
@@ -39,7 +195,11 @@ type PipeListener struct {
 var _ net.Listener = &PipeListener{}
 
 func ListenPipe() *PipeListener {
-	return &PipeListener{conns: make(chan net.Conn), done: make(chan struct{})}
+	return &PipeListener{
+		conns:   make(chan net.Conn),
+		done:    make(chan struct{}),
+		tracked: map[*pipeConn]struct{}{},
+	}
 }
 
 func (p *PipeListener) Accept() (net.Conn, error) {
@@ -61,27 +221,154 @@ func (p *PipeListener) Close() error {
 	return nil
 }
 
+// CloseConns closes both ends of every connection created by p that is
+// still open. It can be called whether p is closed or not.
+func (p *PipeListener) CloseConns() {
+	p.mu.Lock()
+	conns := make([]*pipeConn, 0, len(p.tracked))
+	for conn := range p.tracked {
+		conns = append(conns, conn)
+	}
+	p.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
 func (p *PipeListener) Dial(_, _ string) (net.Conn, error) {
 	return p.DialContext(context.Background(), "", "")
 }
 
-func (p *PipeListener) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
-	s, c := net.Pipe()
+func (p *PipeListener) DialContext(ctx context.Context, _, _ string) (_ net.Conn, err error) {
+	s, c := p.pipe()
 	select {
 	case p.conns <- s:
 		return c, nil
 	case <-p.done:
-		return nil, syscall.ECONNREFUSED
+		err = syscall.ECONNREFUSED
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		err = ctx.Err()
 	}
+	s.Close()
+	c.Close()
+	return nil, err
 }
 
 func (p *PipeListener) DialContextGRPC(ctx context.Context, _ string) (net.Conn, error) {
 	return p.DialContext(ctx, "", "")
 }
 
+var knownNetworks = map[string]bool{
+	"tcp": true, "tcp4": true, "tcp6": true,
+	"udp": true, "udp4": true, "udp6": true,
+	"unix": true, "unixgram": true, "unixpacket": true,
+}
+
+func (p *PipeListener) pipe() (*pipeConn, *pipeConn) {
+	s, c := net.Pipe()
+	ps, pc := &pipeConn{Conn: s, p: p}, &pipeConn{Conn: c, p: p}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tracked[ps] = struct{}{}
+	p.tracked[pc] = struct{}{}
+	return ps, pc
+}
+
+// pipeConn is a net.Conn that stops being tracked by the PipeListener
+// that created it when closed.
+type pipeConn struct {
+	net.Conn
+	p *PipeListener
+}
+
+func (c *pipeConn) Close() error {
+	c.p.mu.Lock()
+	delete(c.p.tracked, c)
+	c.p.mu.Unlock()
+	return c.Conn.Close()
+}
+
+type idleTimeoutConn struct {
+	net.Conn
+	idle  time.Duration
+	timer *time.Timer
+}
+
+func (c *idleTimeoutConn) Close() error {
+	c.timer.Stop()
+	return c.Conn.Close()
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.timer.Reset(c.idle)
+	}
+	return n, err
+}
+
+func (c *idleTimeoutConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.timer.Reset(c.idle)
+	}
+	return n, err
+}
+
+type limitListener struct {
+	net.Listener
+	sem  chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitListenerConn{Conn: conn, release: func() { <-l.sem }}, nil
+}
+
+func (l *limitListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// limitListenerConn is a net.Conn that releases its slot in the
+// limitListener that accepted it when closed.
+type limitListenerConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitListenerConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
 type pipeListenerAddr struct{}
 
 func (pipeListenerAddr) Network() string { return "pipe" }
 func (pipeListenerAddr) String() string  { return "pipe" }
+
+func resolveAddr(addr, ipNetwork, unixNetwork string) (network, address string) {
+	if fields := strings.SplitN(addr, ":", 2); len(fields) == 2 && knownNetworks[fields[0]] {
+		return fields[0], fields[1]
+	}
+	if strings.ContainsRune(addr, '/') {
+		return unixNetwork, addr
+	}
+	return ipNetwork, addr
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: © 2022 Grégoire Duchêne <gduchene@awhk.org>
+// SPDX-License-Identifier: ISC
+
+package core_test
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"go.awhk.org/core"
+)
+
+func TestListen_ReuseAddr(s *testing.T) {
+	t := core.T{T: s}
+
+	l, err := core.Listen("tcp:127.0.0.1:0")
+	t.Must(t.AssertErrorIs(nil, err))
+	addr := l.Addr().String()
+
+	// Closing the server side first leaves it in TIME_WAIT.
+	t.Go(func() {
+		conn, err := l.Accept()
+		if t.AssertErrorIs(nil, err) {
+			conn.Close()
+		}
+	})
+	conn, err := net.Dial("tcp", addr)
+	t.Must(t.AssertErrorIs(nil, err))
+	t.Wait()
+	_, err = conn.Read(make([]byte, 1))
+	t.AssertErrorIs(io.EOF, err)
+	conn.Close()
+	l.Close()
+
+	l, err = core.Listen("tcp:" + addr)
+	t.Must(t.AssertErrorIs(nil, err))
+	l.Close()
+}
+
+func TestListenReusePort(s *testing.T) {
+	t := core.T{T: s}
+
+	l1, err := core.ListenReusePort("tcp:127.0.0.1:0")
+	t.Must(t.AssertErrorIs(nil, err))
+	defer l1.Close()
+
+	l2, err := core.ListenReusePort("tcp:" + l1.Addr().String())
+	t.Must(t.AssertErrorIs(nil, err))
+	defer l2.Close()
+	t.AssertEqual(l1.Addr().String(), l2.Addr().String())
+
+	_, err = core.Listen("tcp:" + l1.Addr().String())
+	t.AssertNotEqual(nil, err)
+}
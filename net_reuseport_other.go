@@ -0,0 +1,12 @@
+// SPDX-FileCopyrightText: © 2022 Grégoire Duchêne <gduchene@awhk.org>
+// SPDX-License-Identifier: ISC
+
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package core
+
+import "syscall"
+
+func controlReusePort(_, _ string, _ syscall.RawConn) error {
+	return ErrReusePortUnsupported
+}
@@ -5,12 +5,313 @@ package core_test
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"syscall"
 	"testing"
+	"time"
 
 	"go.awhk.org/core"
 )
 
+func TestDialRetry(s *testing.T) {
+	t := core.T{T: s}
+
+	// Grab a port that is very likely to be free, and release it right
+	// away so that connections to it are refused.
+	l, err := core.Listen("tcp:127.0.0.1:0")
+	t.Must(t.AssertErrorIs(nil, err))
+	addr := "tcp:" + l.Addr().String()
+	l.Close()
+
+	t.Run("Success", func(t *core.T) {
+		t.Go(func() {
+			time.Sleep(50 * time.Millisecond)
+			l, err := core.Listen(addr)
+			if !t.AssertErrorIs(nil, err) {
+				return
+			}
+			defer l.Close()
+			if conn, err := l.Accept(); t.AssertErrorIs(nil, err) {
+				conn.Close()
+			}
+		})
+
+		conn, err := core.DialRetry(context.Background(), addr, 100, 10*time.Millisecond)
+		if t.AssertErrorIs(nil, err) {
+			conn.Close()
+		}
+	})
+
+	t.Run("WhenAttemptsExhausted", func(t *core.T) {
+		conn, err := core.DialRetry(context.Background(), addr, 3, time.Millisecond)
+		t.AssertErrorIs(syscall.ECONNREFUSED, err)
+		t.AssertNil(conn)
+	})
+
+	t.Run("WhenContextCanceled", func(t *core.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		conn, err := core.DialRetry(ctx, addr, 100, time.Second)
+		t.AssertErrorIs(context.DeadlineExceeded, err)
+		t.AssertNil(conn)
+	})
+}
+
+func TestFreePort(s *testing.T) {
+	t := core.T{T: s}
+
+	port, err := core.FreePort()
+	t.Must(t.AssertErrorIs(nil, err))
+	t.Assert(port > 0)
+
+	l, err := core.Listen("tcp:127.0.0.1:" + strconv.Itoa(port))
+	t.Must(t.AssertErrorIs(nil, err))
+	defer l.Close()
+	t.AssertEqual(port, l.Addr().(*net.TCPAddr).Port)
+}
+
+func TestIdleTimeoutConn(s *testing.T) {
+	t := core.T{T: s}
+
+	t.Run("WhenIdle", func(t *core.T) {
+		s, c := net.Pipe()
+		defer c.Close()
+		conn := core.IdleTimeoutConn(s, 20*time.Millisecond)
+
+		start := time.Now()
+		_, err := conn.Read(make([]byte, 1))
+		t.AssertErrorIs(io.ErrClosedPipe, err)
+		t.Assert(time.Since(start) >= 20*time.Millisecond)
+		_, err = c.Write([]byte("Hello World!"))
+		t.AssertErrorIs(io.ErrClosedPipe, err)
+	})
+
+	t.Run("WhenActive", func(t *core.T) {
+		s, c := net.Pipe()
+		defer c.Close()
+		conn := core.IdleTimeoutConn(s, 50*time.Millisecond)
+		defer conn.Close()
+
+		t.Go(func() { io.Copy(io.Discard, c) })
+		for i := 0; i < 10; i++ {
+			_, err := conn.Write([]byte("Hello World!"))
+			t.AssertErrorIs(nil, err)
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+func TestLimitListener(s *testing.T) {
+	t := core.T{T: s}
+
+	p := t.TempPipeListener()
+	l := core.LimitListener(p, 2)
+
+	accepted := make(chan net.Conn)
+	t.Go(func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	})
+	for i := 0; i < 3; i++ {
+		t.Go(func() {
+			conn, err := p.Dial("", "")
+			if t.AssertErrorIs(nil, err) {
+				t.Cleanup(func() { conn.Close() })
+			}
+		})
+	}
+
+	conn1, conn2 := <-accepted, <-accepted
+	select {
+	case <-accepted:
+		t.Fatal("\nexpected third Accept to block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	conn1.Close()
+	conn1.Close()
+	conn3 := <-accepted
+	conn2.Close()
+	conn3.Close()
+
+	l.Close()
+	_, ok := <-accepted
+	t.AssertEqual(false, ok)
+	t.Wait()
+}
+
+func TestResolveAddr(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		name string
+		addr string
+
+		expNetwork string
+		expAddress string
+	}{
+		{
+			name: "Prefixed",
+			addr: "tcp:127.0.0.1:80",
+
+			expNetwork: "tcp",
+			expAddress: "127.0.0.1:80",
+		},
+		{
+			name: "PrefixedUNIX",
+			addr: "unix:/run/app.sock",
+
+			expNetwork: "unix",
+			expAddress: "/run/app.sock",
+		},
+		{
+			name: "HostPort",
+			addr: "localhost:80",
+
+			expNetwork: "tcp",
+			expAddress: "localhost:80",
+		},
+		{
+			name: "Port",
+			addr: ":80",
+
+			expNetwork: "tcp",
+			expAddress: ":80",
+		},
+		{
+			name: "Path",
+			addr: "/run/app.sock",
+
+			expNetwork: "unix",
+			expAddress: "/run/app.sock",
+		},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			network, address := core.ResolveAddr(tc.addr)
+			t.AssertEqual(tc.expNetwork, network)
+			t.AssertEqual(tc.expAddress, address)
+		})
+	}
+}
+
+func TestContextDialer(s *testing.T) {
+	t := core.T{T: s}
+
+	p := t.TempPipeListener()
+	go http.Serve(p, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, "Hello World!")
+	}))
+
+	var dialer core.ContextDialer = p
+	client := &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}
+	res, err := client.Get("http://pipe/")
+	t.Must(t.AssertErrorIs(nil, err))
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	t.AssertErrorIs(nil, err)
+	t.AssertEqual("Hello World!", string(body))
+}
+
+func TestDialerRegistry(s *testing.T) {
+	t := core.T{T: s}
+
+	var r core.DialerRegistry
+
+	t.Run("Registered", func(t *core.T) {
+		p := t.TempPipeListener()
+		r.Register("backend:80", p.DialContext)
+
+		t.Go(func() {
+			conn, err := p.Accept()
+			if t.AssertErrorIs(nil, err) {
+				conn.Close()
+			}
+		})
+		conn, err := r.DialContext(context.Background(), "tcp", "backend:80")
+		if t.AssertErrorIs(nil, err) {
+			t.AssertEqual("pipe", conn.RemoteAddr().Network())
+			conn.Close()
+		}
+	})
+
+	t.Run("Fallback", func(t *core.T) {
+		l := t.TempListener()
+
+		t.Go(func() {
+			conn, err := l.Accept()
+			if t.AssertErrorIs(nil, err) {
+				conn.Close()
+			}
+		})
+		conn, err := r.DialContext(context.Background(), "tcp", l.Addr().String())
+		if t.AssertErrorIs(nil, err) {
+			t.AssertEqual("tcp", conn.RemoteAddr().Network())
+			conn.Close()
+		}
+	})
+}
+
+func TestListenAll(s *testing.T) {
+	t := core.T{T: s}
+
+	sock := filepath.Join(t.TempDir(), "sock")
+	ls, err := core.ListenAll("tcp:127.0.0.1:0", "unix:"+sock)
+	t.Must(t.AssertErrorIs(nil, err))
+	t.Must(t.AssertEqual(2, len(ls)))
+	t.AssertEqual("tcp", ls[0].Addr().Network())
+	t.AssertEqual("unix", ls[1].Addr().Network())
+	for _, l := range ls {
+		l.Close()
+	}
+
+	t.Run("WhenFailing", func(t *core.T) {
+		sock := filepath.Join(t.TempDir(), "sock")
+		ls, err := core.ListenAll("unix:"+sock, "tcp:127.0.0.1:99999")
+		t.AssertNotNil(err)
+		t.AssertNil(ls)
+
+		// Closing a Unix listener removes its socket file.
+		_, err = os.Stat(sock)
+		t.AssertErrorIs(fs.ErrNotExist, err)
+	})
+}
+
+func TestListenPacket(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, addr := range []string{"udp:127.0.0.1:0", "127.0.0.1:0"} {
+		t.Run(addr, func(t *core.T) {
+			conn, err := core.ListenPacket(addr)
+			t.Must(t.AssertErrorIs(nil, err))
+			defer conn.Close()
+			t.AssertEqual("udp", conn.LocalAddr().Network())
+
+			_, err = conn.WriteTo([]byte("Hello World!"), conn.LocalAddr())
+			t.AssertErrorIs(nil, err)
+
+			buf := make([]byte, 32)
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, from, err := conn.ReadFrom(buf)
+			t.AssertErrorIs(nil, err)
+			t.AssertEqual("Hello World!", string(buf[:n]))
+			t.AssertEqual(conn.LocalAddr().String(), from.String())
+		})
+	}
+}
+
 func TestPipeListener(s *testing.T) {
 	t := core.T{T: s}
 
@@ -20,12 +321,33 @@ func TestPipeListener(s *testing.T) {
 		t.Go(func() {
 			conn, err := p.Accept()
 			t.AssertErrorIs(nil, err)
-			t.AssertNotEqual(nil, conn)
+			t.AssertNotNil(conn)
 		})
 
 		conn, err := p.Dial("", "")
 		t.AssertErrorIs(nil, err)
-		t.AssertNotEqual(nil, conn)
+		t.AssertNotNil(conn)
+	})
+
+	t.Run("CloseConns", func(t *core.T) {
+		p := core.ListenPipe()
+		defer p.Close()
+
+		accepted := make(chan net.Conn, 1)
+		t.Go(func() {
+			conn, err := p.Accept()
+			t.AssertErrorIs(nil, err)
+			accepted <- conn
+		})
+		conn, err := p.Dial("", "")
+		t.AssertErrorIs(nil, err)
+		server := <-accepted
+
+		p.CloseConns()
+		_, err = conn.Write([]byte("Hello World!"))
+		t.AssertErrorIs(io.ErrClosedPipe, err)
+		_, err = server.Read(make([]byte, 1))
+		t.AssertErrorIs(io.ErrClosedPipe, err)
 	})
 
 	t.Run("WhenClosed", func(t *core.T) {
@@ -34,11 +356,11 @@ func TestPipeListener(s *testing.T) {
 
 		conn, err := p.Accept()
 		t.AssertErrorIs(syscall.EINVAL, err)
-		t.AssertEqual(nil, conn)
+		t.AssertNil(conn)
 
 		conn, err = p.Dial("", "")
 		t.AssertErrorIs(syscall.ECONNREFUSED, err)
-		t.AssertEqual(nil, conn)
+		t.AssertNil(conn)
 	})
 
 	t.Run("WhenClosedTwice", func(t *core.T) {
@@ -54,6 +376,6 @@ func TestPipeListener(s *testing.T) {
 		cancel()
 		conn, err := p.DialContext(ctx, "", "")
 		t.AssertErrorIs(context.Canceled, err)
-		t.AssertEqual(nil, conn)
+		t.AssertNil(conn)
 	})
 }
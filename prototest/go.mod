@@ -0,0 +1,16 @@
+module go.awhk.org/core/prototest
+
+go 1.21
+
+require (
+	github.com/google/go-cmp v0.6.0
+	go.awhk.org/core v0.0.0
+	google.golang.org/protobuf v1.33.0
+)
+
+require (
+	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/time v0.5.0 // indirect
+)
+
+replace go.awhk.org/core => ../
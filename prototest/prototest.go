@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: © 2022 Grégoire Duchêne <gduchene@awhk.org>
+// SPDX-License-Identifier: ISC
+
+// Package prototest provides helpers to test code using protocol
+// buffers. It lives in its own module so that users of core that do
+// not need protocol buffers do not depend on them, not even in their
+// go.sum.
+package prototest
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"go.awhk.org/core"
+)
+
+// NewT returns a core.T whose options make assertions compare protocol
+// buffer messages correctly.
+func NewT(t *testing.T) *core.T {
+	return &core.T{T: t, Options: cmp.Options{protocmp.Transform()}}
+}
@@ -0,0 +1,25 @@
+// SPDX-FileCopyrightText: © 2022 Grégoire Duchêne <gduchene@awhk.org>
+// SPDX-License-Identifier: ISC
+
+package prototest_test
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go.awhk.org/core/prototest"
+)
+
+func TestNewT(s *testing.T) {
+	t := prototest.NewT(s)
+
+	exp, err := structpb.NewStruct(map[string]any{"foo": "bar", "baz": 42})
+	t.Must(t.AssertErrorIs(nil, err))
+	actual, err := structpb.NewStruct(map[string]any{"baz": 42, "foo": "bar"})
+	t.Must(t.AssertErrorIs(nil, err))
+	t.AssertEqual(exp, actual)
+
+	actual.Fields["foo"] = structpb.NewStringValue("qux")
+	t.AssertNotEqual(exp, actual)
+}
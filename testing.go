@@ -4,9 +4,19 @@
 package core
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"net"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -17,7 +27,52 @@ type T struct {
 	*testing.T
 	Options cmp.Options
 
-	wg sync.WaitGroup
+	base    *T
+	context string
+	wg      sync.WaitGroup
+}
+
+// AssertRecv checks that a value is received from ch within timeout,
+// and that it is equal to exp. The test fails if ch is closed or if
+// nothing is received in time.
+//
+// AssertRecv is not a method because methods cannot have type
+// parameters.
+func AssertRecv[V any](t *T, ch <-chan V, exp V, timeout time.Duration) bool {
+	t.Helper()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case actual, ok := <-ch:
+		if !ok {
+			t.Errorf("\nexpected to receive %#v, channel was closed", exp)
+			return false
+		}
+		return t.AssertEqual(exp, actual)
+	case <-timer.C:
+		t.Errorf("\nexpected to receive %#v, got nothing after %s", exp, timeout)
+		return false
+	}
+}
+
+// AssertSameFunc checks that a and b return the same output for each
+// of the inputs passed, which is handy to compare two implementations
+// of the same function. Only the first divergent input is reported.
+//
+// AssertSameFunc is not a method because methods cannot have type
+// parameters.
+func AssertSameFunc[I, O any](t *T, inputs []I, a, b func(I) O) bool {
+	t.Helper()
+
+	for _, input := range inputs {
+		if diff := cmp.Diff(a(input), b(input), t.Options...); diff != "" {
+			t.Errorf("\nfunctions diverge for input %#v\n%s", input, diff)
+			return false
+		}
+	}
+	return true
 }
 
 func (t *T) Assert(b bool) bool {
@@ -29,6 +84,52 @@ func (t *T) Assert(b bool) bool {
 	return b
 }
 
+// AssertElementsMatch checks that exp and actual, which must be slices
+// or arrays, hold the same elements the same number of times,
+// regardless of their order. Elements are compared with cmp.Equal
+// and t.Options.
+//
+// Elements are matched pairwise, which is quadratic in the length of
+// the slices, rather than sorted first with cmpopts.SortSlices: they
+// may be of any type and thus have no natural ordering, and a less
+// function consistent with t.Options cannot be derived from them.
+func (t *T) AssertElementsMatch(exp, actual any) bool {
+	t.Helper()
+
+	ev, av := reflect.ValueOf(exp), reflect.ValueOf(actual)
+	if !isList(ev) || !isList(av) {
+		t.Errorf("\nexpected slices or arrays, got %T and %T", exp, actual)
+		return false
+	}
+	var (
+		extra   []any
+		matched = make([]bool, ev.Len())
+	)
+	for i := 0; i < av.Len(); i++ {
+		a, found := av.Index(i).Interface(), false
+		for j := range matched {
+			if !matched[j] && cmp.Equal(ev.Index(j).Interface(), a, t.Options...) {
+				matched[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			extra = append(extra, a)
+		}
+	}
+	var missing []any
+	for j, m := range matched {
+		if !m {
+			missing = append(missing, ev.Index(j).Interface())
+		}
+	}
+	if len(missing) == 0 && len(extra) == 0 {
+		return true
+	}
+	t.Errorf("\nexpected elements of %#v, got %#v\nmissing: %#v\nextra: %#v", exp, actual, missing, extra)
+	return false
+}
+
 func (t *T) AssertEqual(exp, actual any) bool {
 	t.Helper()
 
@@ -60,6 +161,42 @@ func (t *T) AssertErrorIs(target, err error) bool {
 	return false
 }
 
+// AssertJSONEqual checks that exp and actual hold equivalent JSON
+// documents, i.e. documents that only differ by whitespace or the order
+// of object keys. Both are unmarshaled into values of type any, which
+// are then compared like AssertEqual does.
+func (t *T) AssertJSONEqual(exp, actual []byte) bool {
+	t.Helper()
+
+	var ev, av any
+	if err := json.Unmarshal(exp, &ev); err != nil {
+		t.Errorf("\ncould not unmarshal expected JSON %q: %v", exp, err)
+		return false
+	}
+	if err := json.Unmarshal(actual, &av); err != nil {
+		t.Errorf("\ncould not unmarshal actual JSON %q: %v", actual, err)
+		return false
+	}
+	diff := cmp.Diff(ev, av, t.Options...)
+	if diff == "" {
+		return true
+	}
+	t.Errorf("\nexpected JSON %s, got %s\n%s", exp, actual, diff)
+	return false
+}
+
+// AssertNil checks that v is nil. Unlike AssertEqual, typed nils, e.g.
+// a nil pointer stored in an interface, are treated as nil.
+func (t *T) AssertNil(v any) bool {
+	t.Helper()
+
+	if isNil(v) {
+		return true
+	}
+	t.Errorf("\nexpected nil, got %#v", v)
+	return false
+}
+
 func (t *T) AssertPanics(f func()) bool {
 	t.Helper()
 	return t.AssertPanicsWith(f, nil)
@@ -86,6 +223,30 @@ func (t *T) AssertPanicsWith(f func(), exp any) (b bool) {
 	return true
 }
 
+// AssertNoGoroutineLeak records the number of running goroutines, and
+// checks when the test completes that it went back to at most that
+// number, giving goroutines up to a second to exit. It should be called
+// at the beginning of a test. Since goroutines are counted process-wide,
+// results are unreliable when tests run in parallel.
+func (t *T) AssertNoGoroutineLeak() {
+	t.Helper()
+
+	baseline := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		t.Helper()
+
+		deadline := time.Now().Add(time.Second)
+		n := runtime.NumGoroutine()
+		for n > baseline && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			n = runtime.NumGoroutine()
+		}
+		if n > baseline {
+			t.Errorf("\nexpected at most %d goroutines, got %d", baseline, n)
+		}
+	})
+}
+
 func (t *T) AssertNot(b bool) bool {
 	t.Helper()
 
@@ -105,6 +266,18 @@ func (t *T) AssertNotEqual(notExp, actual any) bool {
 	return false
 }
 
+// AssertNotNil checks that v is not nil, with the same semantics as
+// AssertNil.
+func (t *T) AssertNotNil(v any) bool {
+	t.Helper()
+
+	if !isNil(v) {
+		return true
+	}
+	t.Errorf("\nunexpected nil %#v", v)
+	return false
+}
+
 func (t *T) AssertNotPanics(f func()) (b bool) {
 	t.Helper()
 
@@ -119,10 +292,51 @@ func (t *T) AssertNotPanics(f func()) (b bool) {
 	return true
 }
 
+// CaptureLog redirects the output of the standard logger to the
+// returned buffer until the returned function is called or the test
+// completes, whichever comes first.
+//
+// As the standard logger is global to the process, CaptureLog must not
+// be used in parallel tests: messages logged by other tests would end
+// up in the buffer, and restoring the output could race with them.
+func (t *T) CaptureLog() (*bytes.Buffer, func()) {
+	var (
+		buf  = &bytes.Buffer{}
+		once sync.Once
+		prev = log.Writer()
+	)
+	log.SetOutput(buf)
+	restore := func() { once.Do(func() { log.SetOutput(prev) }) }
+	t.Cleanup(restore)
+	return buf, restore
+}
+
+// Error works like testing.T.Error, except the message is prefixed
+// with the context set by WithContext, if any.
+func (t *T) Error(args ...any) {
+	t.Helper()
+	t.T.Error(t.withContext(strings.TrimSuffix(fmt.Sprintln(args...), "\n")))
+}
+
+// Errorf works like testing.T.Errorf, except the message is prefixed
+// with the context set by WithContext, if any.
+func (t *T) Errorf(format string, args ...any) {
+	t.Helper()
+	t.T.Error(t.withContext(fmt.Sprintf(format, args...)))
+}
+
+// Fatalf works like testing.T.Fatalf, except the message is prefixed
+// with the context set by WithContext, if any.
+func (t *T) Fatalf(format string, args ...any) {
+	t.Helper()
+	t.T.Fatal(t.withContext(fmt.Sprintf(format, args...)))
+}
+
 func (t *T) Go(f func()) {
-	t.wg.Add(1)
+	wg := t.waitGroup()
+	wg.Add(1)
 	go func() {
-		defer t.wg.Done()
+		defer wg.Done()
 		f()
 	}()
 }
@@ -133,13 +347,123 @@ func (t *T) Must(b bool) {
 	}
 }
 
+// Repeat runs f n times as subtests named after the iteration index,
+// starting from 0. Each subtest gets its own T, and iterations stop
+// after the first one that fails. This is useful to shake out flaky
+// behavior in concurrent code, especially with the race detector.
+func (t *T) Repeat(n int, f func(t *T)) {
+	for i := 0; i < n; i++ {
+		if !t.run(strconv.Itoa(i), f, false) {
+			return
+		}
+	}
+}
+
 func (t *T) Run(name string, f func(t *T)) {
-	t.T.Run(name, func(s *testing.T) {
-		o := &T{T: s, Options: make(cmp.Options, len(t.Options))}
+	t.run(name, f, false)
+}
+
+// RunParallel works like Run, except the subtest is run in parallel
+// with other parallel subtests. Goroutines started with Go from within
+// the subtest are waited for before the subtest completes, but the
+// parent test will not wait for them as they belong to the subtest.
+func (t *T) RunParallel(name string, f func(t *T)) {
+	t.run(name, f, true)
+}
+
+// TempListener returns a TCP net.Listener bound to a port picked by
+// the system on the loopback interface. The test fails right away if
+// the listener cannot be created, and the listener is closed when the
+// test completes.
+func (t *T) TempListener() net.Listener {
+	t.Helper()
+
+	l, err := Listen("tcp:127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("\ncould not listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// TempPipeListener works like TempListener, except a PipeListener is
+// returned.
+func (t *T) TempPipeListener() *PipeListener {
+	p := ListenPipe()
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func (t *T) Wait() { t.waitGroup().Wait() }
+
+// WithContext returns a T whose failure messages are prefixed with the
+// key-value pairs passed, e.g. the name or input of a table test case.
+// A trailing key without a value is printed on its own.
+// The T returned shares its Options with t, and goroutines started
+// with its Go method are waited for by t.Wait. Context accumulates
+// when WithContext is called on a T it returned.
+func (t *T) WithContext(kv ...any) *T {
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(t.context, ":"))
+	for i := 0; i < len(kv); i += 2 {
+		if b.Len() == 0 {
+			b.WriteString("\n")
+		} else {
+			b.WriteString(" ")
+		}
+		if i+1 < len(kv) {
+			fmt.Fprintf(&b, "%v=%#v", kv[i], kv[i+1])
+		} else {
+			fmt.Fprint(&b, kv[i])
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(":")
+	}
+	base := t
+	if t.base != nil {
+		base = t.base
+	}
+	return &T{T: t.T, Options: t.Options, base: base, context: b.String()}
+}
+
+func (t *T) run(name string, f func(t *T), parallel bool) bool {
+	return t.T.Run(name, func(s *testing.T) {
+		if parallel {
+			s.Parallel()
+		}
+		o := &T{T: s, Options: make(cmp.Options, len(t.Options)), context: t.context}
 		copy(o.Options, t.Options)
 		f(o)
 		o.wg.Wait()
 	})
 }
 
-func (t *T) Wait() { t.wg.Wait() }
+func (t *T) withContext(msg string) string {
+	if t.context == "" || strings.HasPrefix(msg, "\n") {
+		return t.context + msg
+	}
+	return t.context + " " + msg
+}
+
+func (t *T) waitGroup() *sync.WaitGroup {
+	if t.base != nil {
+		return &t.base.wg
+	}
+	return &t.wg
+}
+
+func isList(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch val := reflect.ValueOf(v); val.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice, reflect.UnsafePointer:
+		return val.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,314 @@
+// SPDX-FileCopyrightText: © 2022 Grégoire Duchêne <gduchene@awhk.org>
+// SPDX-License-Identifier: ISC
+
+package core_test
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.awhk.org/core"
+)
+
+func TestAssertRecv(s *testing.T) {
+	t := core.T{T: s}
+
+	ch := make(chan int, 1)
+	ch <- 42
+	t.Assert(core.AssertRecv(&t, ch, 42, time.Second))
+
+	t.Run("WhenFailing", func(t *core.T) {
+		out, ok := runSubprocess(t, "TestAssertRecv_Failing")
+		t.AssertNot(ok)
+		t.Assert(strings.Contains(out, "expected to receive 42, got nothing after 10ms"))
+		t.Assert(strings.Contains(out, "expected to receive 42, channel was closed"))
+	})
+}
+
+func TestAssertRecv_Failing(s *testing.T) {
+	if !inSubprocess() {
+		s.Skip("only run by TestAssertRecv")
+	}
+	t := core.T{T: s}
+	ch := make(chan int)
+	core.AssertRecv(&t, ch, 42, 10*time.Millisecond)
+	close(ch)
+	core.AssertRecv(&t, ch, 42, time.Second)
+}
+
+func TestAssertSameFunc(s *testing.T) {
+	t := core.T{T: s}
+
+	t.Run("Success", func(t *core.T) {
+		core.AssertSameFunc(t, []int{-1, 0, 1, 42}, func(x int) int { return x * 2 }, func(x int) int { return x + x })
+	})
+
+	t.Run("WhenDiverging", func(t *core.T) {
+		out, ok := runSubprocess(t, "TestAssertSameFunc_Diverging")
+		t.AssertNot(ok)
+		t.Assert(strings.Contains(out, "functions diverge for input 1"))
+		t.AssertNot(strings.Contains(out, "input 2"))
+	})
+}
+
+func TestAssertSameFunc_Diverging(s *testing.T) {
+	if !inSubprocess() {
+		s.Skip("only run by TestAssertSameFunc")
+	}
+	t := core.T{T: s}
+	core.AssertSameFunc(&t, []int{0, 1, 2, 3}, func(x int) int { return x * 2 }, func(x int) int { return x * x })
+}
+
+func TestT_AssertElementsMatch(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertElementsMatch([]int{}, ([]int)(nil))
+	t.AssertElementsMatch([]int{1, 2, 2, 3}, []int{2, 3, 2, 1})
+	t.AssertElementsMatch([2]string{"foo", "bar"}, []string{"bar", "foo"})
+
+	t.Run("WhenFailing", func(t *core.T) {
+		out, ok := runSubprocess(t, "TestT_AssertElementsMatch_Failing")
+		t.AssertNot(ok)
+		t.Assert(strings.Contains(out, "missing: []interface {}{2}\n        extra: []interface {}(nil)"))
+		t.Assert(strings.Contains(out, "missing: []interface {}{3}\n        extra: []interface {}{4}"))
+		t.Assert(strings.Contains(out, "expected slices or arrays, got int and []int"))
+	})
+}
+
+func TestT_AssertElementsMatch_Failing(s *testing.T) {
+	if !inSubprocess() {
+		s.Skip("only run by TestT_AssertElementsMatch")
+	}
+	t := core.T{T: s}
+	t.AssertElementsMatch([]int{1, 2, 2}, []int{2, 1})
+	t.AssertElementsMatch([]int{1, 2, 3}, []int{2, 1, 4})
+	t.AssertElementsMatch(1, []int{1})
+}
+
+func TestT_AssertJSONEqual(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertJSONEqual([]byte(`{"foo": [1, 2], "bar": {"baz": null}}`), []byte(`{"bar":{"baz":null},"foo":[1,2]}`))
+	t.AssertJSONEqual([]byte(`42`), []byte(" 42.0\n"))
+
+	t.Run("WhenFailing", func(t *core.T) {
+		out, ok := runSubprocess(t, "TestT_AssertJSONEqual_Failing")
+		t.AssertNot(ok)
+		t.Assert(strings.Contains(out, `expected JSON {"foo": [1, 2]}, got {"foo": [2, 1]}`))
+		t.Assert(strings.Contains(out, "could not unmarshal actual JSON"))
+	})
+}
+
+func TestT_AssertJSONEqual_Failing(s *testing.T) {
+	if !inSubprocess() {
+		s.Skip("only run by TestT_AssertJSONEqual")
+	}
+	t := core.T{T: s}
+	t.AssertJSONEqual([]byte(`{"foo": [1, 2]}`), []byte(`{"foo": [2, 1]}`))
+	t.AssertJSONEqual([]byte(`{}`), []byte(`{`))
+}
+
+func TestT_AssertNil(s *testing.T) {
+	t := core.T{T: s}
+
+	var (
+		conn net.Conn
+		ptr  *int
+		m    map[string]int
+	)
+	t.AssertNil(nil)
+	t.AssertNil(conn)
+	t.AssertNil(ptr)
+	t.AssertNil(m)
+	t.AssertNil(net.Conn((*net.TCPConn)(nil)))
+}
+
+func TestT_AssertNotNil(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertNotNil(0)
+	t.AssertNotNil("")
+	t.AssertNotNil(&struct{}{})
+	t.AssertNotNil(map[string]int{})
+}
+
+func TestT_AssertNoGoroutineLeak(s *testing.T) {
+	t := core.T{T: s}
+
+	t.Run("Success", func(t *core.T) {
+		t.AssertNoGoroutineLeak()
+		done := make(chan struct{})
+		go func() { <-done }()
+		close(done)
+	})
+
+	t.Run("WhenLeaking", func(t *core.T) {
+		out, ok := runSubprocess(t, "TestT_AssertNoGoroutineLeak_Leaking")
+		t.AssertNot(ok)
+		t.Assert(strings.Contains(out, "goroutines, got"))
+	})
+}
+
+func TestT_AssertNoGoroutineLeak_Leaking(s *testing.T) {
+	if !inSubprocess() {
+		s.Skip("only run by TestT_AssertNoGoroutineLeak")
+	}
+	t := core.T{T: s}
+	t.AssertNoGoroutineLeak()
+	go func() { select {} }()
+}
+
+func TestT_CaptureLog(s *testing.T) {
+	t := core.T{T: s}
+
+	prev := log.Writer()
+	buf, restore := t.CaptureLog()
+	log.Print("Hello World!")
+	restore()
+	t.Assert(strings.HasSuffix(buf.String(), "Hello World!\n"))
+	t.Assert(log.Writer() == prev)
+}
+
+func TestT_Repeat(s *testing.T) {
+	t := core.T{T: s}
+
+	var iters []string
+	t.Repeat(3, func(t *core.T) { iters = append(iters, t.Name()) })
+	t.AssertEqual([]string{"TestT_Repeat/0", "TestT_Repeat/1", "TestT_Repeat/2"}, iters)
+
+	t.Run("WhenFailing", func(t *core.T) {
+		out, ok := runSubprocess(t, "TestT_Repeat_Failing")
+		t.AssertNot(ok)
+		t.Assert(strings.Contains(out, "--- PASS: TestT_Repeat_Failing/1"))
+		t.Assert(strings.Contains(out, "--- FAIL: TestT_Repeat_Failing/2"))
+		t.AssertNot(strings.Contains(out, "TestT_Repeat_Failing/3"))
+	})
+}
+
+func TestT_Repeat_Failing(s *testing.T) {
+	if !inSubprocess() {
+		s.Skip("only run by TestT_Repeat")
+	}
+	t := core.T{T: s}
+	i := 0
+	t.Repeat(5, func(t *core.T) {
+		t.AssertNotEqual(2, i)
+		i++
+	})
+}
+
+func TestT_RunParallel(s *testing.T) {
+	t := core.T{T: s}
+
+	var n int32
+	t.Run("Group", func(t *core.T) {
+		for _, name := range []string{"First", "Second"} {
+			t.RunParallel(name, func(t *core.T) {
+				t.Go(func() {
+					time.Sleep(10 * time.Millisecond)
+					atomic.AddInt32(&n, 1)
+				})
+			})
+		}
+	})
+	t.AssertEqual(int32(2), atomic.LoadInt32(&n))
+}
+
+func TestT_TempListener(s *testing.T) {
+	t := core.T{T: s}
+
+	var l net.Listener
+	t.Run("Use", func(t *core.T) {
+		l = t.TempListener()
+		t.Go(func() {
+			if conn, err := l.Accept(); t.AssertErrorIs(nil, err) {
+				conn.Close()
+			}
+		})
+		conn, err := net.Dial(l.Addr().Network(), l.Addr().String())
+		if t.AssertErrorIs(nil, err) {
+			conn.Close()
+		}
+	})
+	_, err := l.Accept()
+	t.AssertErrorIs(net.ErrClosed, err)
+}
+
+func TestT_TempPipeListener(s *testing.T) {
+	t := core.T{T: s}
+
+	var p *core.PipeListener
+	t.Run("Use", func(t *core.T) {
+		p = t.TempPipeListener()
+		t.Go(func() {
+			if conn, err := p.Accept(); t.AssertErrorIs(nil, err) {
+				conn.Close()
+			}
+		})
+		conn, err := p.Dial("", "")
+		if t.AssertErrorIs(nil, err) {
+			conn.Close()
+		}
+	})
+	_, err := p.Accept()
+	t.AssertErrorIs(syscall.EINVAL, err)
+}
+
+func TestT_WithContext(s *testing.T) {
+	t := core.T{T: s}
+
+	var ran bool
+	t.WithContext("name", "foo").Go(func() { ran = true })
+	t.Wait()
+	t.Assert(ran)
+
+	t.Run("WhenFailing", func(t *core.T) {
+		out, ok := runSubprocess(t, "TestT_WithContext_Failing")
+		t.AssertNot(ok)
+		t.Assert(strings.Contains(out, "name=\"foo\" input=42:\n        expected 84, got 42"))
+		t.Assert(strings.Contains(out, "name=\"foo\" input=42 extra:\n        expected value to be true"))
+		t.Assert(strings.Contains(out, "\n        expected value to be false"))
+		t.AssertNot(strings.Contains(out, ":\n        expected value to be false"))
+		t.Assert(strings.Contains(out, "name=\"foo\" input=42: expected 42 got 43"))
+		t.Assert(strings.Contains(out, ": unexpected 1 2\n"))
+	})
+}
+
+func TestT_WithContext_Failing(s *testing.T) {
+	if !inSubprocess() {
+		s.Skip("only run by TestT_WithContext")
+	}
+	t := core.T{T: s}
+	ct := t.WithContext("name", "foo", "input", 42)
+	ct.AssertEqual(84, 42)
+	ct.WithContext("extra").Assert(false)
+	t.AssertNot(true)
+	ct.Error("expected", 42, "got", 43)
+	t.Error("unexpected", 1, 2)
+}
+
+// runSubprocess runs the named test in a separate process, so that
+// failures can be checked without failing the current test. The output
+// of the test is returned, along with whether it succeeded.
+func runSubprocess(t *core.T, name string) (string, bool) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^"+name+"$", "-test.v")
+	cmd.Env = append(os.Environ(), "CORE_TEST_SUBPROCESS=1")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("\ncould not run %s: %v", name, err)
+	}
+	return string(out), err == nil
+}
+
+func inSubprocess() bool { return os.Getenv("CORE_TEST_SUBPROCESS") == "1" }
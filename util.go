@@ -3,6 +3,49 @@
 
 package core
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Clamp returns lo if v is less than lo, hi if v is greater than hi,
+// and v otherwise. Clamp panics if lo is greater than hi.
+func Clamp[T Ordered](v, lo, hi T) T {
+	if lo > hi {
+		panic("core.Clamp: lo must not be greater than hi")
+	}
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// Coalesce returns the first value that is not the zero value of T, or
+// the zero value if there is no such value.
+func Coalesce[T comparable](vals ...T) T {
+	var zero T
+	for _, val := range vals {
+		if val != zero {
+			return val
+		}
+	}
+	return zero
+}
+
+// IsZero reports whether v is the zero value of its type. Since T must
+// be comparable, IsZero cannot be used with slices, maps, or functions.
+func IsZero[T comparable](v T) bool {
+	var zero T
+	return v == zero
+}
+
 // MapKeys returns a slice containing all the keys of the map supplied.
 // It basically is https://pkg.go.dev/golang.org/x/exp/maps#Keys, but
 // that package is still unstable.
@@ -17,6 +60,26 @@ func MapKeys[T ~map[K]V, K comparable, V any](m T) []K {
 	return ret
 }
 
+// MapMerge returns a new map containing all the entries of the maps
+// supplied. When a key is present in several maps, the value from the
+// last one wins. MapMerge returns nil if no maps are supplied.
+func MapMerge[M ~map[K]V, K comparable, V any](ms ...M) M {
+	if len(ms) == 0 {
+		return nil
+	}
+	size := 0
+	for _, m := range ms {
+		size += len(m)
+	}
+	ret := make(M, size)
+	for _, m := range ms {
+		for k, v := range m {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
 // Must panics if err is not nil. It returns val otherwise.
 func Must[T any](val T, err error) T {
 	if err != nil {
@@ -25,6 +88,100 @@ func Must[T any](val T, err error) T {
 	return val
 }
 
+// Mustf works like Must, except the error is wrapped with a message
+// built from format and args, so that it is easier to tell what failed.
+// The panic value is an error that wraps err.
+func Mustf[T any](val T, err error, format string, args ...any) T {
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), err))
+	}
+	return val
+}
+
+// Retry calls f until it succeeds, up to attempts times in total,
+// waiting for backoff after each failure or until ctx is canceled. The
+// last error returned by f is returned if it never succeeded, or the
+// error of ctx if it was canceled while waiting.
+func Retry[T any](ctx context.Context, attempts int, backoff time.Duration, f func() (T, error)) (T, error) {
+	return RetryBackoff(ctx, attempts, func(int) time.Duration { return backoff }, f)
+}
+
+// RetryBackoff works like Retry, except the time to wait after each
+// failure is returned by backoff, which is passed the number of
+// attempts made so far, starting at 1. This allows for exponential
+// backoff, for instance.
+func RetryBackoff[T any](ctx context.Context, attempts int, backoff func(int) time.Duration, f func() (T, error)) (T, error) {
+	for i := 1; ; i++ {
+		val, err := f()
+		if err == nil || i >= attempts {
+			return val, err
+		}
+		timer := time.NewTimer(backoff(i))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// SliceChunk splits a slice into chunks of size elements, the last
+// chunk holding the remaining elements. Chunks share the backing array
+// of the slice passed, but their capacity is capped so that appending
+// to a chunk does not overwrite the next one. SliceChunk panics if size
+// is not positive.
+func SliceChunk[S any](ts []S, size int) [][]S {
+	if size <= 0 {
+		panic("core.SliceChunk: size must be positive")
+	}
+	if len(ts) == 0 {
+		return nil
+	}
+	ret := make([][]S, 0, (len(ts)+size-1)/size)
+	for i := 0; i < len(ts); i += size {
+		j := i + size
+		if j > len(ts) {
+			j = len(ts)
+		}
+		ret = append(ret, ts[i:j:j])
+	}
+	return ret
+}
+
+// SliceFlatten returns a new slice made of the elements of the slices
+// passed, in order. SliceFlatten returns nil if there are no elements.
+func SliceFlatten[S any](tss [][]S) []S {
+	size := 0
+	for _, ts := range tss {
+		size += len(ts)
+	}
+	if size == 0 {
+		return nil
+	}
+	ret := make([]S, 0, size)
+	for _, ts := range tss {
+		ret = append(ret, ts...)
+	}
+	return ret
+}
+
+// SliceGroupBy groups the elements of the slice passed by the key
+// returned by key for each of them. Elements keep their relative order
+// within each group. SliceGroupBy returns nil if the slice is empty.
+func SliceGroupBy[S any, K comparable](ts []S, key func(S) K) map[K][]S {
+	if len(ts) == 0 {
+		return nil
+	}
+	ret := map[K][]S{}
+	for _, t := range ts {
+		k := key(t)
+		ret[k] = append(ret[k], t)
+	}
+	return ret
+}
+
 // SliceMap applies a function to a slice and returns a new slice made
 // of the returned values.
 func SliceMap[T ~[]S, S, U any](f func(S) U, ts T) []U {
@@ -38,6 +195,123 @@ func SliceMap[T ~[]S, S, U any](f func(S) U, ts T) []U {
 	return ret
 }
 
+// SliceMapIndex works like SliceMap, except the index of each element
+// is also passed to the function.
+func SliceMapIndex[T ~[]S, S, U any](f func(int, S) U, ts T) []U {
+	if len(ts) == 0 {
+		return nil
+	}
+	ret := make([]U, len(ts))
+	for i, t := range ts {
+		ret[i] = f(i, t)
+	}
+	return ret
+}
+
+// SlicePartition returns two new slices, the first made of the
+// elements of the slice passed for which f returns true, and the second
+// of the others. Elements keep their relative order. Either slice is
+// nil if it would be empty.
+func SlicePartition[S any](f func(S) bool, ts []S) (matching, rest []S) {
+	for _, t := range ts {
+		if f(t) {
+			matching = append(matching, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+	return matching, rest
+}
+
+// SliceReverse reverses the order of the elements of the slice passed
+// in place.
+func SliceReverse[S any](ts []S) {
+	for i, j := 0, len(ts)-1; i < j; i, j = i+1, j-1 {
+		ts[i], ts[j] = ts[j], ts[i]
+	}
+}
+
+// SliceReversed returns a new slice made of the elements of the slice
+// passed in reverse order. SliceReversed returns nil if the slice is
+// empty.
+func SliceReversed[S any](ts []S) []S {
+	if len(ts) == 0 {
+		return nil
+	}
+	ret := make([]S, len(ts))
+	for i, t := range ts {
+		ret[len(ts)-1-i] = t
+	}
+	return ret
+}
+
+// SliceUnique returns a new slice made of the elements of the slice
+// passed, without duplicates. Elements are kept in the order they first
+// appeared.
+func SliceUnique[S comparable](ts []S) []S {
+	if len(ts) == 0 {
+		return nil
+	}
+	var (
+		ret  = make([]S, 0, len(ts))
+		seen = make(map[S]struct{}, len(ts))
+	)
+	for _, t := range ts {
+		if _, found := seen[t]; !found {
+			seen[t] = struct{}{}
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// SliceValidate calls validate on every element of the slice passed,
+// and returns the errors returned joined with errors.Join, or nil if
+// there were none. Each error is wrapped with the index of the element
+// it was returned for.
+func SliceValidate[S any](ts []S, validate func(int, S) error) error {
+	var errs []error
+	for i, t := range ts {
+		if err := validate(i, t); err != nil {
+			errs = append(errs, fmt.Errorf("element %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SortedMapEntries works like SortedMapKeys, except both keys and
+// values are returned.
+func SortedMapEntries[T ~map[K]V, K Ordered, V any](m T) []MapEntry[K, V] {
+	if len(m) == 0 {
+		return nil
+	}
+	ret := make([]MapEntry[K, V], 0, len(m))
+	for k, v := range m {
+		ret = append(ret, MapEntry[K, V]{k, v})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Key < ret[j].Key })
+	return ret
+}
+
+// SortedMapKeys works like MapKeys, except the keys are sorted.
+func SortedMapKeys[T ~map[K]V, K Ordered, V any](m T) []K {
+	ret := MapKeys(m)
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// Zero returns the zero value of T.
+func Zero[T any]() T {
+	var zero T
+	return zero
+}
+
+// MapEntry is a key-value pair from a map.
+type MapEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
 // NoCopy flags a type that embeds it as not to be copied. Go does not
 // prevent values from being copied, but ‘go vet’ will pick it up and
 // signal it, which can then be caught by many CI/CD pipelines.
@@ -48,3 +322,90 @@ type NoCopy struct{}
 
 func (*NoCopy) Lock()   {}
 func (*NoCopy) Unlock() {}
+
+// Optional holds a value of type T that may or may not be set, which
+// tells apart values that were not set from zero values. The zero value
+// of Optional is None.
+//
+// Optional values are marshaled to and unmarshaled from JSON as their
+// underlying value, with None represented by null.
+type Optional[T any] struct {
+	val T
+	ok  bool
+}
+
+var (
+	_ json.Marshaler   = Optional[int]{}
+	_ json.Unmarshaler = &Optional[int]{}
+)
+
+// None returns an Optional that is not set.
+func None[T any]() Optional[T] { return Optional[T]{} }
+
+// Some returns an Optional set to v.
+func Some[T any](v T) Optional[T] { return Optional[T]{v, true} }
+
+// Get returns the value of the Optional and true if it is set, or the
+// zero value of T and false otherwise.
+func (o Optional[T]) Get() (T, bool) { return o.val, o.ok }
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.ok {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.val)
+}
+
+// OrElse returns the value of the Optional if it is set, or def
+// otherwise.
+func (o Optional[T]) OrElse(def T) T {
+	if o.ok {
+		return o.val
+	}
+	return def
+}
+
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*o = None[T]()
+		return nil
+	}
+	var val T
+	if err := json.Unmarshal(b, &val); err != nil {
+		return err
+	}
+	*o = Some(val)
+	return nil
+}
+
+// Ordered is a constraint that permits any type that supports the
+// ordering operators. It basically is
+// https://pkg.go.dev/golang.org/x/exp/constraints#Ordered, but that
+// package is still unstable.
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 |
+		~string
+}
+
+// Result holds the outcome of an operation producing a T, so that it
+// can be passed around or stored as a single value.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Must returns the value of the Result, or panics with its error if it
+// is not nil.
+func (r Result[T]) Must() T { return Must(r.Value, r.Err) }
+
+// Unwrap returns the value and the error of the Result.
+func (r Result[T]) Unwrap() (T, error) { return r.Value, r.Err }
+
+// Signed is a constraint that permits any signed integer type. It
+// basically is https://pkg.go.dev/golang.org/x/exp/constraints#Signed,
+// but that package is still unstable.
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
@@ -4,8 +4,12 @@
 package core_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -13,12 +17,57 @@ import (
 	"go.awhk.org/core"
 )
 
+func TestClamp(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(1, core.Clamp(-5, 1, 10))
+	t.AssertEqual(1, core.Clamp(1, 1, 10))
+	t.AssertEqual(5, core.Clamp(5, 1, 10))
+	t.AssertEqual(10, core.Clamp(10, 1, 10))
+	t.AssertEqual(10, core.Clamp(42, 1, 10))
+	t.AssertEqual(4.2, core.Clamp(4.2, 4.2, 4.2))
+	t.AssertEqual("b", core.Clamp("z", "a", "b"))
+	t.AssertPanics(func() { core.Clamp(5, 10, 1) })
+}
+
+func TestCoalesce(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual("", core.Coalesce[string]())
+	t.AssertEqual("", core.Coalesce("", ""))
+	t.AssertEqual("foo", core.Coalesce("", "foo", "bar"))
+	t.AssertEqual(0, core.Coalesce(0, 0, 0))
+	t.AssertEqual(42, core.Coalesce(0, 42, 84))
+}
+
+func TestIsZero(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(true, core.IsZero(0))
+	t.AssertEqual(true, core.IsZero(""))
+	t.AssertEqual(true, core.IsZero((*int)(nil)))
+	t.AssertEqual(false, core.IsZero(42))
+	t.AssertEqual(false, core.IsZero("42"))
+}
+
 func TestMapKeys(s *testing.T) {
-	t := core.T{T: s, Options: cmp.Options{sortStrings}}
+	t := core.T{T: s}
 
 	t.AssertEqual(([]string)(nil), core.MapKeys[map[string]int](nil))
 	t.AssertEqual(([]string)(nil), core.MapKeys(map[string]int{}))
-	t.AssertEqual([]string{"bar", "foo"}, core.MapKeys(map[string]int{"foo": 1, "bar": 2}))
+	t.AssertElementsMatch([]string{"bar", "foo"}, core.MapKeys(map[string]int{"foo": 1, "bar": 2}))
+}
+
+func TestMapMerge(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(map[string]int(nil), core.MapMerge[map[string]int]())
+	t.AssertEqual(map[string]int{}, core.MapMerge[map[string]int](nil, nil))
+	t.AssertEqual(map[string]int{"foo": 1, "bar": 3, "baz": 4, "qux": 5}, core.MapMerge(
+		map[string]int{"foo": 1, "bar": 2},
+		map[string]int{"bar": 3, "baz": 3},
+		map[string]int{"baz": 4, "qux": 5},
+	))
 }
 
 func TestMust(s *testing.T) {
@@ -30,6 +79,179 @@ func TestMust(s *testing.T) {
 	t.AssertEqual(42, core.Must(42, nil))
 }
 
+func TestMustf(s *testing.T) {
+	t := core.T{T: s}
+
+	err := errors.New("some error")
+	t.AssertNotPanics(func() { core.Mustf(42, nil, "could not %s", "fail") })
+	t.AssertEqual(42, core.Mustf(42, nil, "could not %s", "fail"))
+
+	defer func() {
+		actual, ok := recover().(error)
+		if t.Assert(ok) {
+			t.AssertEqual("could not do something: some error", actual.Error())
+			t.AssertErrorIs(err, actual)
+		}
+	}()
+	core.Mustf(42, err, "could not %s", "do something")
+}
+
+func TestOptional(s *testing.T) {
+	t := core.T{T: s}
+
+	val, ok := core.Some(0).Get()
+	t.AssertEqual(0, val)
+	t.AssertEqual(true, ok)
+	t.AssertEqual(0, core.Some(0).OrElse(42))
+
+	val, ok = core.None[int]().Get()
+	t.AssertEqual(0, val)
+	t.AssertEqual(false, ok)
+	t.AssertEqual(42, core.None[int]().OrElse(42))
+
+	var zero core.Optional[int]
+	_, ok = zero.Get()
+	t.AssertEqual(false, ok)
+
+	t.Run("JSON", func(t *core.T) {
+		type config struct {
+			Port    core.Optional[int]    `json:"port"`
+			Host    core.Optional[string] `json:"host"`
+			Verbose core.Optional[bool]   `json:"verbose"`
+		}
+
+		b, err := json.Marshal(config{Port: core.Some(0), Host: core.None[string](), Verbose: core.Some(true)})
+		t.AssertErrorIs(nil, err)
+		t.AssertEqual(`{"port":0,"host":null,"verbose":true}`, string(b))
+
+		var c config
+		t.AssertErrorIs(nil, json.Unmarshal([]byte(`{"port":0,"host":null}`), &c))
+		port, ok := c.Port.Get()
+		t.AssertEqual(0, port)
+		t.AssertEqual(true, ok)
+		_, ok = c.Host.Get()
+		t.AssertEqual(false, ok)
+		_, ok = c.Verbose.Get()
+		t.AssertEqual(false, ok)
+
+		c.Host = core.Some("localhost")
+		t.AssertErrorIs(nil, json.Unmarshal([]byte(`{"host":null}`), &c))
+		_, ok = c.Host.Get()
+		t.AssertEqual(false, ok)
+
+		var typeErr *json.UnmarshalTypeError
+		t.AssertErrorAs(&typeErr, json.Unmarshal([]byte(`{"port":"80"}`), &c))
+	})
+}
+
+func TestResult(s *testing.T) {
+	t := core.T{T: s, Options: []cmp.Option{cmpopts.EquateErrors()}}
+
+	val, err := core.Result[int]{Value: 42}.Unwrap()
+	t.AssertEqual(42, val)
+	t.AssertErrorIs(nil, err)
+	t.AssertEqual(42, core.Result[int]{Value: 42}.Must())
+
+	errFailed := errors.New("failed")
+	results := []core.Result[int]{{Value: 42}, {Err: errFailed}}
+	val, err = results[1].Unwrap()
+	t.AssertEqual(0, val)
+	t.AssertErrorIs(errFailed, err)
+	t.AssertPanicsWith(func() { results[1].Must() }, errFailed)
+}
+
+func TestRetry(s *testing.T) {
+	t := core.T{T: s}
+
+	errFailed := errors.New("failed")
+	failUntil := func(n int, calls *int) func() (int, error) {
+		return func() (int, error) {
+			if *calls++; *calls < n {
+				return 0, fmt.Errorf("%w %d", errFailed, *calls)
+			}
+			return 42, nil
+		}
+	}
+
+	t.Run("Success", func(t *core.T) {
+		var calls int
+		val, err := core.Retry(context.Background(), 3, time.Millisecond, failUntil(3, &calls))
+		t.AssertErrorIs(nil, err)
+		t.AssertEqual(42, val)
+		t.AssertEqual(3, calls)
+	})
+
+	t.Run("WhenExhausted", func(t *core.T) {
+		var calls int
+		val, err := core.Retry(context.Background(), 3, time.Millisecond, failUntil(5, &calls))
+		t.AssertErrorIs(errFailed, err)
+		t.AssertEqual("failed 3", err.Error())
+		t.AssertEqual(0, val)
+		t.AssertEqual(3, calls)
+	})
+
+	t.Run("WhenContextCanceled", func(t *core.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var calls int
+		_, err := core.Retry(ctx, 3, time.Hour, failUntil(5, &calls))
+		t.AssertErrorIs(context.Canceled, err)
+		t.AssertEqual(1, calls)
+	})
+
+	t.Run("WithBackoff", func(t *core.T) {
+		var (
+			calls    int
+			backoffs []int
+		)
+		backoff := func(n int) time.Duration {
+			backoffs = append(backoffs, n)
+			return time.Duration(n) * time.Millisecond
+		}
+		val, err := core.RetryBackoff(context.Background(), 5, backoff, failUntil(4, &calls))
+		t.AssertErrorIs(nil, err)
+		t.AssertEqual(42, val)
+		t.AssertEqual([]int{1, 2, 3}, backoffs)
+	})
+}
+
+func TestSliceChunk(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(([][]int)(nil), core.SliceChunk([]int{}, 2))
+	t.AssertEqual([][]int{{1, 2}, {3, 4}}, core.SliceChunk([]int{1, 2, 3, 4}, 2))
+	t.AssertEqual([][]int{{1, 2}, {3, 4}, {5}}, core.SliceChunk([]int{1, 2, 3, 4, 5}, 2))
+	t.AssertEqual([][]int{{1, 2, 3}}, core.SliceChunk([]int{1, 2, 3}, 5))
+	t.AssertPanics(func() { core.SliceChunk([]int{1}, 0) })
+
+	ts := []int{1, 2, 3, 4}
+	chunks := core.SliceChunk(ts, 2)
+	_ = append(chunks[0], 42)
+	t.AssertEqual([]int{1, 2, 3, 4}, ts)
+}
+
+func TestSliceFlatten(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(([]int)(nil), core.SliceFlatten[int](nil))
+	t.AssertEqual(([]int)(nil), core.SliceFlatten([][]int{{}, nil}))
+	t.AssertEqual([]int{1, 2, 3, 4, 5, 6}, core.SliceFlatten([][]int{{1}, {}, {2, 3, 4}, nil, {5, 6}}))
+
+	flat := core.SliceFlatten([][]int{{1, 2}, {3}})
+	t.AssertEqual(3, cap(flat))
+}
+
+func TestSliceGroupBy(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(map[int][]string(nil), core.SliceGroupBy([]string{}, func(s string) int { return len(s) }))
+	t.AssertEqual(map[int][]string{
+		3: {"foo", "bar", "baz"},
+		5: {"hello"},
+		6: {"foobar", "barbaz"},
+	}, core.SliceGroupBy([]string{"foo", "hello", "bar", "foobar", "baz", "barbaz"}, func(s string) int { return len(s) }))
+}
+
 func TestSliceMap(s *testing.T) {
 	t := core.T{T: s}
 
@@ -38,4 +260,121 @@ func TestSliceMap(s *testing.T) {
 	t.AssertEqual([]int{42, 84}, core.SliceMap(func(x int) int { return x * 2 }, []int{21, 42}))
 }
 
-var sortStrings = cmpopts.SortSlices(func(s, t string) bool { return s <= t })
+func TestSliceMapIndex(s *testing.T) {
+	t := core.T{T: s}
+
+	item := func(i int, s string) string { return fmt.Sprintf("%s %d", s, i) }
+	t.AssertEqual(([]string)(nil), core.SliceMapIndex(item, ([]string)(nil)))
+	t.AssertEqual(([]string)(nil), core.SliceMapIndex(item, []string{}))
+	t.AssertEqual([]string{"item 0", "item 1"}, core.SliceMapIndex(item, []string{"item", "item"}))
+}
+
+func TestSlicePartition(s *testing.T) {
+	t := core.T{T: s}
+	even := func(x int) bool { return x%2 == 0 }
+
+	matching, rest := core.SlicePartition(even, []int{})
+	t.AssertEqual(([]int)(nil), matching)
+	t.AssertEqual(([]int)(nil), rest)
+
+	matching, rest = core.SlicePartition(even, []int{1, 2, 3, 4, 5})
+	t.AssertEqual([]int{2, 4}, matching)
+	t.AssertEqual([]int{1, 3, 5}, rest)
+
+	matching, rest = core.SlicePartition(even, []int{2, 4})
+	t.AssertEqual([]int{2, 4}, matching)
+	t.AssertEqual(([]int)(nil), rest)
+}
+
+func TestSliceReverse(s *testing.T) {
+	t := core.T{T: s}
+
+	for _, tc := range []struct {
+		name  string
+		input []int
+
+		exp []int
+	}{
+		{name: "Empty", input: []int{}, exp: []int{}},
+		{name: "One", input: []int{1}, exp: []int{1}},
+		{name: "Even", input: []int{1, 2, 3, 4}, exp: []int{4, 3, 2, 1}},
+		{name: "Odd", input: []int{1, 2, 3}, exp: []int{3, 2, 1}},
+	} {
+		t.Run(tc.name, func(t *core.T) {
+			core.SliceReverse(tc.input)
+			t.AssertEqual(tc.exp, tc.input)
+		})
+	}
+}
+
+func TestSliceReversed(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(([]int)(nil), core.SliceReversed([]int{}))
+	t.AssertEqual([]int{4, 3, 2, 1}, core.SliceReversed([]int{1, 2, 3, 4}))
+
+	ts := []int{1, 2, 3}
+	t.AssertEqual([]int{3, 2, 1}, core.SliceReversed(ts))
+	t.AssertEqual([]int{1, 2, 3}, ts)
+}
+
+func TestSliceUnique(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(([]int)(nil), core.SliceUnique([]int{}))
+	t.AssertEqual([]int{3, 1, 2}, core.SliceUnique([]int{3, 1, 3, 2, 1, 3}))
+	t.AssertEqual([]string{"foo", "bar"}, core.SliceUnique([]string{"foo", "bar"}))
+}
+
+func TestSliceValidate(s *testing.T) {
+	t := core.T{T: s}
+
+	var (
+		errNegative = errors.New("negative")
+		errZero     = errors.New("zero")
+	)
+	validate := func(_ int, x int) error {
+		switch {
+		case x < 0:
+			return errNegative
+		case x == 0:
+			return errZero
+		}
+		return nil
+	}
+
+	t.AssertErrorIs(nil, core.SliceValidate([]int{}, validate))
+	t.AssertErrorIs(nil, core.SliceValidate([]int{1, 2}, validate))
+
+	err := core.SliceValidate([]int{1, -1, 0, 2, -2}, validate)
+	t.AssertErrorIs(errNegative, err)
+	t.AssertErrorIs(errZero, err)
+	t.AssertEqual("element 1: negative\nelement 2: zero\nelement 4: negative", err.Error())
+}
+
+func TestSortedMapEntries(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(([]core.MapEntry[string, int])(nil), core.SortedMapEntries(map[string]int{}))
+	t.AssertEqual(
+		[]core.MapEntry[string, int]{{"bar", 2}, {"baz", 3}, {"foo", 1}},
+		core.SortedMapEntries(map[string]int{"foo": 1, "bar": 2, "baz": 3}),
+	)
+}
+
+func TestSortedMapKeys(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(([]int)(nil), core.SortedMapKeys(map[int]string{}))
+	for i := 0; i < 10; i++ {
+		t.AssertEqual([]int{-1, 2, 42, 84}, core.SortedMapKeys(map[int]string{42: "", 2: "", 84: "", -1: ""}))
+	}
+}
+
+func TestZero(s *testing.T) {
+	t := core.T{T: s}
+
+	t.AssertEqual(0, core.Zero[int]())
+	t.AssertEqual("", core.Zero[string]())
+	t.AssertEqual(([]int)(nil), core.Zero[[]int]())
+}